@@ -0,0 +1,109 @@
+package typed
+
+// AggQueue is a FIFO queue that keeps the aggregate of its items under a
+// Monoid, so Push, Pop and Aggregate are amortized O(1) even when the
+// operation has no inverse, like min or gcd. It uses two AggStacks: new
+// items go to the back stack, and the front stack is refilled from it in
+// reverse when empty.
+type AggQueue[T, A any] struct {
+	front  *AggStack[T, A] // oldest item on top
+	back   *AggStack[T, A] // newest item on top
+	monoid Monoid[A]
+}
+
+func NewAggQueue[T, A any](monoid Monoid[A], lift func(T) A, opts ...StackOption) *AggQueue[T, A] {
+	// The front stack holds items in reverse, so it combines in reverse
+	// to keep the oldest item on the left.
+	reversed := Monoid[A]{
+		Identity: monoid.Identity,
+		Combine:  func(a, b A) A { return monoid.Combine(b, a) },
+	}
+	return &AggQueue[T, A]{
+		front:  NewAggStack[T, A](reversed, lift, opts...),
+		back:   NewAggStack[T, A](monoid, lift, opts...),
+		monoid: monoid,
+	}
+}
+
+func (q *AggQueue[T, A]) Push(val T) {
+	q.back.Push(val)
+}
+
+// Pop removes and returns the oldest item.
+func (q *AggQueue[T, A]) Pop() (T, bool) {
+	q.refill()
+	return q.front.Pop()
+}
+
+// Peek returns the oldest item without removing it.
+func (q *AggQueue[T, A]) Peek() (T, bool) {
+	q.refill()
+	return q.front.Peek()
+}
+
+// Aggregate returns the aggregate of all items from oldest to newest, or
+// Identity when empty.
+func (q *AggQueue[T, A]) Aggregate() A {
+	switch {
+	case q.front.IsEmpty():
+		return q.back.Aggregate()
+	case q.back.IsEmpty():
+		return q.front.Aggregate()
+	}
+	return q.monoid.Combine(q.front.Aggregate(), q.back.Aggregate())
+}
+
+// Evict removes up to n of the oldest items and returns how many were
+// removed.
+func (q *AggQueue[T, A]) Evict(n int) int {
+	removed := 0
+	for removed < n {
+		if _, ok := q.Pop(); !ok {
+			break
+		}
+		removed++
+	}
+	return removed
+}
+
+// EvictWhile removes the oldest items as long as fn returns true for
+// them, and returns how many were removed.
+func (q *AggQueue[T, A]) EvictWhile(fn func(T) bool) int {
+	removed := 0
+	for {
+		val, ok := q.Peek()
+		if !ok || !fn(val) {
+			return removed
+		}
+		q.front.Pop()
+		removed++
+	}
+}
+
+func (q *AggQueue[T, A]) Len() int {
+	return q.front.Len() + q.back.Len()
+}
+
+func (q *AggQueue[T, A]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+func (q *AggQueue[T, A]) Reset() {
+	q.front.Reset()
+	q.back.Reset()
+}
+
+// refill moves the back stack onto the front stack when the front is
+// empty, reversing the order so the oldest item ends up on top.
+func (q *AggQueue[T, A]) refill() {
+	if !q.front.IsEmpty() {
+		return
+	}
+	for {
+		val, ok := q.back.Pop()
+		if !ok {
+			return
+		}
+		q.front.Push(val)
+	}
+}
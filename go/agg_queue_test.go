@@ -0,0 +1,146 @@
+package typed
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestAggQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+	concat := Monoid[string]{Combine: func(a, b string) string { return a + b }}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "keeps order",
+			steps: []step{
+				{"aggregate", nil, ""},
+				{"push", "a", "a"},
+				{"push", "b", "ab"},
+				{"push", "c", "abc"},
+				{"pop", "a", "bc"},
+				{"push", "d", "bcd"},
+				{"peek", "b", "bcd"},
+				{"pop", "b", "cd"},
+				{"pop", "c", "d"},
+				{"pop", "d", ""},
+				{"pop", nil, ""},
+			},
+		},
+		{
+			name: "evict",
+			steps: []step{
+				{"push", "a", "a"},
+				{"push", "b", "ab"},
+				{"push", "c", "abc"},
+				{"push", "d", "abcd"},
+				{"evict", 2, "cd"},
+				{"evictWhile", "c", "d"},
+				{"evictWhile", "c", "d"},
+				{"evict", 5, ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewAggQueue(concat, func(s string) string { return s })
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					q.Push(step.value.(string))
+				case "pop", "peek":
+					var val string
+					var ok bool
+					if step.op == "pop" {
+						val, ok = q.Pop()
+					} else {
+						val, ok = q.Peek()
+					}
+					if step.value == nil {
+						if ok {
+							t.Errorf("step %d: %s expected to fail, got %v", i, step.op, val)
+						}
+					} else if !ok || val != step.value.(string) {
+						t.Errorf("step %d: %s expected %v, got %v (ok=%v)", i, step.op, step.value, val, ok)
+					}
+				case "evict":
+					q.Evict(step.value.(int))
+				case "evictWhile":
+					q.EvictWhile(func(s string) bool { return s <= step.value.(string) })
+				case "aggregate":
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+				// Every step checks the aggregate afterwards
+				if got := q.Aggregate(); got != step.expected.(string) {
+					t.Errorf("step %d: aggregate after %s expected %q, got %q", i, step.op, step.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAggQueue_Model(t *testing.T) {
+	gcd := func(a, b int) int {
+		for b != 0 {
+			a, b = b, a%b
+		}
+		return a
+	}
+	r := rand.New(rand.NewSource(1))
+	q := NewAggQueue(Monoid[int]{Combine: gcd}, func(v int) int { return v },
+		WithStackLimitOptions(WithShrinkThresholdCap(4)))
+	var model []int
+
+	for op := 0; op < 5000; op++ {
+		switch r.Intn(4) {
+		case 0:
+			if _, ok := q.Pop(); ok {
+				model = model[1:]
+			}
+		case 1:
+			n := r.Intn(3)
+			n = q.Evict(n)
+			model = model[n:]
+		default:
+			v := 6 * (r.Intn(20) + 1)
+			q.Push(v)
+			model = append(model, v)
+		}
+		if q.Len() != len(model) {
+			t.Fatalf("op %d: expected len %d, got %d", op, len(model), q.Len())
+		}
+		want := 0
+		for _, v := range model {
+			want = gcd(want, v)
+		}
+		if got := q.Aggregate(); got != want {
+			t.Fatalf("op %d: expected gcd %d, got %d", op, want, got)
+		}
+	}
+}
+
+// Example of using AggQueue
+func ExampleAggQueue() {
+	// Rolling maximum over the last 3 readings
+	q := NewAggQueue(Monoid[int]{Combine: func(a, b int) int { return max(a, b) }}, func(v int) int { return v })
+
+	for _, reading := range []int{5, 9, 2, 4, 1} {
+		q.Push(reading)
+		if q.Len() > 3 {
+			q.Evict(1)
+		}
+	}
+	peak := q.Aggregate() // 4
+
+	// Prevent unused variable warnings in example
+	_ = peak
+}
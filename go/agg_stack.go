@@ -0,0 +1,91 @@
+package typed
+
+// Monoid is an associative Combine operation with an Identity value, used
+// to aggregate the items of AggStack and AggQueue.
+type Monoid[A any] struct {
+	Identity A
+	Combine  func(a, b A) A
+}
+
+type aggEntry[T, A any] struct {
+	val T
+	agg A // aggregate of this item and everything below it
+}
+
+// AggStack is a Stack that keeps the aggregate of its items under a
+// Monoid, so Aggregate is O(1) after every Push and Pop. Each item is
+// mapped to the aggregate type with lift; Combine is never called with
+// Identity, so operations without a true identity, like min, work too.
+type AggStack[T, A any] struct {
+	stack  *Stack[aggEntry[T, A]]
+	monoid Monoid[A]
+	lift   func(T) A
+}
+
+func NewAggStack[T, A any](monoid Monoid[A], lift func(T) A, opts ...StackOption) *AggStack[T, A] {
+	return &AggStack[T, A]{
+		stack:  NewStack[aggEntry[T, A]](opts...),
+		monoid: monoid,
+		lift:   lift,
+	}
+}
+
+// NewMinStack creates an AggStack whose Aggregate is the smallest item
+// according to less. Aggregate returns the zero value when empty.
+func NewMinStack[T any](less Comparator[T], opts ...StackOption) *AggStack[T, T] {
+	return NewAggStack(Monoid[T]{Combine: func(a, b T) T {
+		if less(b, a) {
+			return b
+		}
+		return a
+	}}, func(v T) T { return v }, opts...)
+}
+
+// NewMaxStack creates an AggStack whose Aggregate is the largest item
+// according to less. Aggregate returns the zero value when empty.
+func NewMaxStack[T any](less Comparator[T], opts ...StackOption) *AggStack[T, T] {
+	return NewAggStack(Monoid[T]{Combine: func(a, b T) T {
+		if less(a, b) {
+			return b
+		}
+		return a
+	}}, func(v T) T { return v }, opts...)
+}
+
+func (s *AggStack[T, A]) Push(val T) {
+	agg := s.lift(val)
+	if top, ok := s.stack.Peek(); ok {
+		agg = s.monoid.Combine(top.agg, agg)
+	}
+	s.stack.Push(aggEntry[T, A]{val: val, agg: agg})
+}
+
+func (s *AggStack[T, A]) Pop() (T, bool) {
+	e, ok := s.stack.Pop()
+	return e.val, ok
+}
+
+func (s *AggStack[T, A]) Peek() (T, bool) {
+	e, ok := s.stack.Peek()
+	return e.val, ok
+}
+
+// Aggregate returns the aggregate of all items, or Identity when empty.
+func (s *AggStack[T, A]) Aggregate() A {
+	if top, ok := s.stack.Peek(); ok {
+		return top.agg
+	}
+	return s.monoid.Identity
+}
+
+func (s *AggStack[T, A]) Len() int {
+	return s.stack.Len()
+}
+
+func (s *AggStack[T, A]) IsEmpty() bool {
+	return s.stack.IsEmpty()
+}
+
+func (s *AggStack[T, A]) Reset() {
+	s.stack.Reset()
+}
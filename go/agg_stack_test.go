@@ -0,0 +1,151 @@
+package typed
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestAggStack(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+	less := func(a, b int) bool { return a < b }
+
+	tests := []struct {
+		name  string
+		new   func() *AggStack[int, int]
+		steps []step
+	}{
+		{
+			name: "min",
+			new:  func() *AggStack[int, int] { return NewMinStack[int](less) },
+			steps: []step{
+				{"aggregate", nil, 0},
+				{"push", 5, 5},
+				{"push", 7, 5},
+				{"push", 2, 2},
+				{"push", 2, 2},
+				{"pop", 2, 2},
+				{"pop", 2, 5},
+				{"pop", 7, 5},
+				{"pop", 5, 0},
+				{"pop", nil, 0},
+			},
+		},
+		{
+			name: "max",
+			new:  func() *AggStack[int, int] { return NewMaxStack[int](less) },
+			steps: []step{
+				{"push", 3, 3},
+				{"push", 1, 3},
+				{"push", 9, 9},
+				{"pop", 9, 3},
+			},
+		},
+		{
+			name: "sum",
+			new: func() *AggStack[int, int] {
+				return NewAggStack(Monoid[int]{Identity: 0, Combine: func(a, b int) int { return a + b }},
+					func(v int) int { return v })
+			},
+			steps: []step{
+				{"aggregate", nil, 0},
+				{"push", 3, 3},
+				{"push", 4, 7},
+				{"peek", 4, 7},
+				{"pop", 4, 3},
+				{"len", nil, 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.new()
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					s.Push(step.value.(int))
+				case "pop", "peek":
+					var val int
+					var ok bool
+					if step.op == "pop" {
+						val, ok = s.Pop()
+					} else {
+						val, ok = s.Peek()
+					}
+					if step.value == nil {
+						if ok {
+							t.Errorf("step %d: %s expected to fail, got %v", i, step.op, val)
+						}
+					} else if !ok || val != step.value.(int) {
+						t.Errorf("step %d: %s expected %v, got %v (ok=%v)", i, step.op, step.value, val, ok)
+					}
+				case "aggregate":
+				case "len":
+					if got := s.Len(); got != step.expected.(int) {
+						t.Errorf("step %d: len expected %v, got %v", i, step.expected, got)
+					}
+					continue
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+				// Every step checks the aggregate afterwards
+				if got := s.Aggregate(); got != step.expected.(int) {
+					t.Errorf("step %d: aggregate after %s expected %v, got %v", i, step.op, step.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAggStack_Model(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := NewMinStack[int](func(a, b int) bool { return a < b },
+		WithStackLimitOptions(WithShrinkThresholdCap(4)))
+	var model []int
+
+	for op := 0; op < 5000; op++ {
+		if r.Intn(3) > 0 || len(model) == 0 {
+			v := r.Intn(1000)
+			s.Push(v)
+			model = append(model, v)
+		} else {
+			s.Pop()
+			model = model[:len(model)-1]
+		}
+		if len(model) == 0 {
+			continue
+		}
+		want := model[0]
+		for _, v := range model {
+			want = min(want, v)
+		}
+		if got := s.Aggregate(); got != want {
+			t.Fatalf("op %d: expected min %d, got %d", op, want, got)
+		}
+	}
+}
+
+// Example of using AggStack
+func ExampleAggStack() {
+	// Minimum of everything on the stack, in O(1)
+	s := NewMinStack[int](func(a, b int) bool { return a < b })
+	s.Push(4)
+	s.Push(1)
+	s.Push(3)
+	smallest := s.Aggregate() // 1
+
+	// Any monoid works, for example the total length of the strings
+	lengths := NewAggStack(Monoid[int]{Combine: func(a, b int) int { return a + b }},
+		func(s string) int { return len(s) })
+	lengths.Push("abc")
+	lengths.Push("de")
+	total := lengths.Aggregate() // 5
+
+	// Prevent unused variable warnings in example
+	_, _ = smallest, total
+}
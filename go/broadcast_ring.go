@@ -0,0 +1,110 @@
+package typed
+
+import "sync/atomic"
+
+// broadcastEvent is one published event. Slots hold pointers to immutable
+// events, so a reader racing with the writer sees either the old event or
+// the new one, never a torn value.
+type broadcastEvent[T any] struct {
+	seq uint64
+	val T
+}
+
+// BroadcastRing is a fixed-capacity ring that one writer publishes to and
+// any number of cursors read from independently. The writer never waits
+// for readers: once it laps a cursor, the oldest unread events are
+// overwritten and Next reports how many were missed.
+//
+// Every slot carries the sequence number of the event it holds, so
+// Publish and Next need no lock. They are safe for concurrent use, but
+// each cursor should only be read by one goroutine at a time.
+type BroadcastRing[T any] struct {
+	next  atomic.Uint64 // sequence number of the next event
+	slots []atomic.Pointer[broadcastEvent[T]]
+}
+
+func NewBroadcastRing[T any](capacity int) *BroadcastRing[T] {
+	if capacity <= 0 {
+		panic("BroadcastRing capacity must be greater than 0")
+	}
+	return &BroadcastRing[T]{slots: make([]atomic.Pointer[broadcastEvent[T]], capacity)}
+}
+
+// Publish appends val, overwriting the oldest event when the ring is full.
+func (r *BroadcastRing[T]) Publish(val T) {
+	e := &broadcastEvent[T]{seq: r.next.Add(1) - 1, val: val}
+	slot := &r.slots[e.seq%uint64(len(r.slots))]
+	for {
+		// A concurrent Publish that lapped this one already owns the slot
+		old := slot.Load()
+		if old != nil && old.seq > e.seq {
+			return
+		}
+		if slot.CompareAndSwap(old, e) {
+			return
+		}
+	}
+}
+
+// Subscribe returns a cursor that sees every event published after the
+// call.
+func (r *BroadcastRing[T]) Subscribe() *BroadcastCursor[T] {
+	return &BroadcastCursor[T]{ring: r, seq: r.next.Load()}
+}
+
+// SubscribeOldest returns a cursor that starts at the oldest event still
+// held by the ring.
+func (r *BroadcastRing[T]) SubscribeOldest() *BroadcastCursor[T] {
+	seq := r.next.Load()
+	if c := uint64(len(r.slots)); seq > c {
+		seq -= c
+	} else {
+		seq = 0
+	}
+	return &BroadcastCursor[T]{ring: r, seq: seq}
+}
+
+// Published returns the total number of events published.
+func (r *BroadcastRing[T]) Published() uint64 {
+	return r.next.Load()
+}
+
+func (r *BroadcastRing[T]) Cap() int {
+	return len(r.slots)
+}
+
+// BroadcastCursor is one reader's position in a BroadcastRing.
+type BroadcastCursor[T any] struct {
+	ring *BroadcastRing[T]
+	seq  uint64
+}
+
+// Next returns the cursor's next event. missed is the number of events
+// overwritten since the previous call before this cursor could read them.
+// ok is false when the cursor has caught up with the writer.
+func (c *BroadcastCursor[T]) Next() (val T, missed uint64, ok bool) {
+	n := uint64(len(c.ring.slots))
+	for seq := c.seq; ; {
+		e := c.ring.slots[seq%n].Load()
+		switch {
+		case e == nil || e.seq < seq:
+			// Not published yet. The cursor stays put, so a lap seen on
+			// the way is counted again by the next call.
+			return val, 0, false
+		case e.seq == seq:
+			missed = seq - c.seq
+			c.seq = seq + 1
+			return e.val, missed, true
+		default:
+			// The writer lapped this cursor, and every event the slot
+			// held before e is gone
+			seq = e.seq - n + 1
+		}
+	}
+}
+
+// Lag returns how many events have been published that the cursor has
+// not read, including any that were already overwritten.
+func (c *BroadcastCursor[T]) Lag() uint64 {
+	return c.ring.next.Load() - c.seq
+}
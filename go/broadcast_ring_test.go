@@ -0,0 +1,234 @@
+package typed
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestBroadcastRing(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+	type event struct {
+		val    int
+		missed uint64
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "readers consume independently",
+			steps: []step{
+				{"subscribe", "a", nil},
+				{"publish", 1, nil},
+				{"subscribe", "b", nil},
+				{"publish", 2, nil},
+				{"next", "a", event{1, 0}},
+				{"next", "a", event{2, 0}},
+				{"next", "a", nil},
+				{"lag", "b", uint64(1)},
+				{"next", "b", event{2, 0}},
+				{"next", "b", nil},
+			},
+		},
+		{
+			name: "writer laps a slow reader",
+			steps: []step{
+				{"subscribe", "slow", nil},
+				{"publishMany", 10, nil},
+				{"lag", "slow", uint64(10)},
+				{"next", "slow", event{6, 6}},
+				{"next", "slow", event{7, 0}},
+				{"publishMany", 6, nil},
+				{"next", "slow", event{12, 4}},
+			},
+		},
+		{
+			name: "subscribe from oldest",
+			steps: []step{
+				{"publishMany", 6, nil},
+				{"subscribeOldest", "a", nil},
+				{"next", "a", event{2, 0}},
+				{"published", nil, uint64(6)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewBroadcastRing[int](4)
+			cursors := map[string]*BroadcastCursor[int]{}
+			published := 0
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "publish":
+					r.Publish(step.value.(int))
+				case "publishMany":
+					for j := 0; j < step.value.(int); j++ {
+						r.Publish(published)
+						published++
+					}
+				case "subscribe":
+					cursors[step.value.(string)] = r.Subscribe()
+				case "subscribeOldest":
+					cursors[step.value.(string)] = r.SubscribeOldest()
+				case "next":
+					val, missed, ok := cursors[step.value.(string)].Next()
+					if step.expected == nil {
+						if ok {
+							t.Errorf("step %d: next expected to fail, got %v (missed=%d)", i, val, missed)
+						}
+					} else if got := (event{val, missed}); !ok || got != step.expected.(event) {
+						t.Errorf("step %d: next expected %v, got %v (ok=%v)", i, step.expected, got, ok)
+					}
+				case "lag":
+					if got := cursors[step.value.(string)].Lag(); got != step.expected.(uint64) {
+						t.Errorf("step %d: lag expected %v, got %v", i, step.expected, got)
+					}
+				case "published":
+					if got := r.Published(); got != step.expected.(uint64) {
+						t.Errorf("step %d: published expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestBroadcastRing_InvalidCapacity(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for zero capacity")
+		}
+	}()
+	NewBroadcastRing[int](0)
+}
+
+func TestBroadcastRing_Concurrent(t *testing.T) {
+	const events = 20000
+	r := NewBroadcastRing[int](64)
+
+	var wg sync.WaitGroup
+	for reader := 0; reader < 4; reader++ {
+		c := r.Subscribe()
+		wg.Add(1)
+		go func(reader int) {
+			defer wg.Done()
+			want := 0
+			for want < events {
+				val, missed, ok := c.Next()
+				if !ok {
+					runtime.Gosched()
+					continue
+				}
+				// Every event is either read or counted as missed
+				if val != want+int(missed) {
+					t.Errorf("reader %d: expected %d after %d missed, got %d", reader, want+int(missed), missed, val)
+					return
+				}
+				want = val + 1
+				if reader == 0 && val%100 == 0 {
+					runtime.Gosched() // fall behind now and then
+				}
+			}
+		}(reader)
+	}
+
+	for i := 0; i < events; i++ {
+		r.Publish(i)
+		if i%16 == 0 {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+}
+
+func TestBroadcastRing_ConcurrentPublish(t *testing.T) {
+	const writers, events = 4, 5000
+	r := NewBroadcastRing[int](8)
+
+	// Readers never hold up writers, so every event is either read or missed
+	var readers sync.WaitGroup
+	var writing sync.WaitGroup
+	writing.Add(writers)
+	done := make(chan struct{})
+	for reader := 0; reader < 2; reader++ {
+		c := r.Subscribe()
+		readers.Add(1)
+		go func(reader int) {
+			defer readers.Done()
+			var seen uint64
+			for {
+				_, missed, ok := c.Next()
+				if ok {
+					seen += 1 + missed
+					continue
+				}
+				select {
+				case <-done:
+					if c.Lag() == 0 {
+						if seen != writers*events {
+							t.Errorf("reader %d: expected %d events read or missed, got %d", reader, writers*events, seen)
+						}
+						return
+					}
+				default:
+					runtime.Gosched()
+				}
+			}
+		}(reader)
+	}
+
+	for w := 0; w < writers; w++ {
+		go func(w int) {
+			defer writing.Done()
+			for i := 0; i < events; i++ {
+				r.Publish(w*events + i)
+			}
+		}(w)
+	}
+	writing.Wait()
+	close(done)
+	readers.Wait()
+
+	// The last Cap events are all still held, each exactly once
+	c := r.SubscribeOldest()
+	seen := map[int]bool{}
+	for {
+		val, missed, ok := c.Next()
+		if !ok {
+			break
+		}
+		if missed != 0 || seen[val] {
+			t.Fatalf("expected each held event once, got %d again (missed=%d)", val, missed)
+		}
+		seen[val] = true
+	}
+	if len(seen) != r.Cap() {
+		t.Errorf("expected %d held events, got %d", r.Cap(), len(seen))
+	}
+}
+
+// Example of using BroadcastRing
+func ExampleBroadcastRing() {
+	// Keep the last 1024 events for every subscriber
+	r := NewBroadcastRing[string](1024)
+	c := r.Subscribe()
+
+	// The writer never blocks, even if subscribers fall behind
+	r.Publish("deploy started")
+
+	// missed counts events overwritten before this subscriber read them
+	event, missed, ok := c.Next()
+
+	// Prevent unused variable warnings in example
+	_, _, _ = event, missed, ok
+}
@@ -0,0 +1,45 @@
+package typed
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec converts values to and from bytes for containers that store their
+// items outside of memory.
+type Codec[T any] interface {
+	Encode(val T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// JSONCodec encodes values with encoding/json.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(val T) ([]byte, error) {
+	return json.Marshal(val)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var val T
+	err := json.Unmarshal(data, &val)
+	return val, err
+}
+
+// GobCodec encodes values with encoding/gob. Each value is encoded as a
+// self-contained gob stream, so values can be decoded in any order.
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Encode(val T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(val); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var val T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&val)
+	return val, err
+}
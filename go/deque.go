@@ -1,14 +1,20 @@
 package typed
 
+import "slices"
+
 type DequeOptions struct {
 	LimitOptions
+	GrowthOptions
+	ResizeHooks
+	SegmentSize int // Block size of the segmented backend, 0 for a contiguous ring
 }
 
 type DequeOption func(*DequeOptions)
 
 func defaultDequeOptions() DequeOptions {
 	return DequeOptions{
-		LimitOptions: DefaultLimitOptions(),
+		LimitOptions:  DefaultLimitOptions(),
+		GrowthOptions: DefaultGrowthOptions(),
 	}
 }
 
@@ -20,11 +26,44 @@ func WithDequeLimitOptions(limitOpts ...LimitOption) DequeOption {
 	}
 }
 
+func WithDequeGrowthOptions(growthOpts ...GrowthOption) DequeOption {
+	return func(do *DequeOptions) {
+		for _, opt := range growthOpts {
+			opt(&do.GrowthOptions)
+		}
+	}
+}
+
+func WithDequeResizeHooks(hooks ...ResizeHook) DequeOption {
+	return func(do *DequeOptions) {
+		for _, hook := range hooks {
+			hook(&do.ResizeHooks)
+		}
+	}
+}
+
+// WithDequeSegmentSize stores the deque as a list of fixed-size blocks
+// instead of one ring buffer. Growing allocates a single block and
+// shrinking releases empty blocks, so pushes and pops never copy the deque.
+// LimitOptions and GrowthOptions do not apply to the segmented backend.
+func WithDequeSegmentSize(size int) DequeOption {
+	if size <= 0 {
+		panic("Segment size must be greater than 0")
+	}
+	return func(do *DequeOptions) {
+		do.SegmentSize = size
+	}
+}
+
 type Deque[T any] struct {
 	data        []T
 	front, back int
 	size        int
+	seg         *segments[T]
 	opts        DequeOptions
+	stats       Stats
+	// shared is set while a Snapshot may still read data
+	shared bool
 }
 
 func NewDeque[T any](opts ...DequeOption) *Deque[T] {
@@ -32,28 +71,51 @@ func NewDeque[T any](opts ...DequeOption) *Deque[T] {
 	for _, opt := range opts {
 		opt(&o)
 	}
+	if o.SegmentSize > 0 {
+		d := &Deque[T]{opts: o}
+		d.seg = newSegments[T](o.SegmentSize, &d.stats, o.ResizeHooks)
+		return d
+	}
 	return &Deque[T]{
-		data: make([]T, 4),
+		data: make([]T, o.InitialCapacity),
 		opts: o,
 	}
 }
 
 func (d *Deque[T]) PushFront(val T) {
+	if d.seg != nil {
+		d.seg.pushFront(val)
+		d.size++
+		d.stats.recordPush(d.size)
+		return
+	}
 	if d.size == len(d.data) {
 		d.grow()
+	} else if d.shared {
+		d.unshare()
 	}
 	d.front = (d.front - 1 + len(d.data)) % len(d.data)
 	d.data[d.front] = val
 	d.size++
+	d.stats.recordPush(d.size)
 }
 
 func (d *Deque[T]) PushBack(val T) {
+	if d.seg != nil {
+		d.seg.pushBack(val)
+		d.size++
+		d.stats.recordPush(d.size)
+		return
+	}
 	if d.size == len(d.data) {
 		d.grow()
+	} else if d.shared {
+		d.unshare()
 	}
 	d.data[d.back] = val
 	d.back = (d.back + 1) % len(d.data)
 	d.size++
+	d.stats.recordPush(d.size)
 }
 
 func (d *Deque[T]) PopFront() (T, bool) {
@@ -61,6 +123,11 @@ func (d *Deque[T]) PopFront() (T, bool) {
 	if d.size == 0 {
 		return zero, false
 	}
+	d.stats.recordPop()
+	if d.seg != nil {
+		d.size--
+		return d.seg.popFront()
+	}
 	val := d.data[d.front]
 	d.front = (d.front + 1) % len(d.data)
 	d.size--
@@ -73,6 +140,11 @@ func (d *Deque[T]) PopBack() (T, bool) {
 	if d.size == 0 {
 		return zero, false
 	}
+	d.stats.recordPop()
+	if d.seg != nil {
+		d.size--
+		return d.seg.popBack()
+	}
 	d.back = (d.back - 1 + len(d.data)) % len(d.data)
 	val := d.data[d.back]
 	d.size--
@@ -85,6 +157,9 @@ func (d *Deque[T]) PeekFront() (T, bool) {
 	if d.size == 0 {
 		return zero, false
 	}
+	if d.seg != nil {
+		return d.seg.front()
+	}
 	return d.data[d.front], true
 }
 
@@ -93,14 +168,263 @@ func (d *Deque[T]) PeekBack() (T, bool) {
 	if d.size == 0 {
 		return zero, false
 	}
+	if d.seg != nil {
+		return d.seg.back()
+	}
 	return d.data[(d.back-1+len(d.data))%len(d.data)], true
 }
 
+// Insert places val at index i, so that it ends up i items from the
+// front. It moves whichever side of i is shorter. Insert panics if i is
+// not in [0, Size()].
+func (d *Deque[T]) Insert(i int, val T) {
+	if i < 0 || i > d.size {
+		panic("Deque index out of range")
+	}
+	if d.seg != nil {
+		d.seg.insert(i, val)
+		d.size++
+		d.stats.recordPush(d.size)
+		return
+	}
+	if d.size == len(d.data) {
+		d.grow()
+	} else if d.shared {
+		d.unshare()
+	}
+	n := len(d.data)
+	if i < d.size/2 {
+		d.front = (d.front - 1 + n) % n
+		for k := 0; k < i; k++ {
+			d.data[(d.front+k)%n] = d.data[(d.front+k+1)%n]
+		}
+	} else {
+		for k := d.size; k > i; k-- {
+			d.data[(d.front+k)%n] = d.data[(d.front+k-1)%n]
+		}
+		d.back = (d.back + 1) % n
+	}
+	d.data[(d.front+i)%n] = val
+	d.size++
+	d.stats.recordPush(d.size)
+}
+
+// RemoveAt removes and returns the item at index i, moving whichever side
+// of i is shorter. It returns false if i is out of range.
+func (d *Deque[T]) RemoveAt(i int) (T, bool) {
+	var zero T
+	if i < 0 || i >= d.size {
+		return zero, false
+	}
+	if d.seg != nil {
+		c := d.seg.cursor(i)
+		val := *c.ptr()
+		d.seg.removeRange(i, i+1)
+		d.size--
+		d.stats.recordPop()
+		return val, true
+	}
+	val := d.data[(d.front+i)%len(d.data)]
+	d.removeRange(i, i+1)
+	return val, true
+}
+
+// RemoveRange removes the items at indexes [i, j), moving whichever side
+// of the range is shorter. It panics if the range is not within
+// [0, Size()].
+func (d *Deque[T]) RemoveRange(i, j int) {
+	if i < 0 || j < i || j > d.size {
+		panic("Deque range out of bounds")
+	}
+	if i == j {
+		return
+	}
+	if d.seg != nil {
+		d.seg.removeRange(i, j)
+		d.size -= j - i
+		d.stats.Pops += uint64(j - i)
+		return
+	}
+	d.removeRange(i, j)
+}
+
+func (d *Deque[T]) removeRange(i, j int) {
+	if d.shared {
+		d.unshare()
+	}
+	var zero T
+	n, m := len(d.data), j-i
+	if i < d.size-j {
+		for k := i - 1; k >= 0; k-- {
+			d.data[(d.front+k+m)%n] = d.data[(d.front+k)%n]
+		}
+		for k := 0; k < m; k++ {
+			d.data[(d.front+k)%n] = zero
+		}
+		d.front = (d.front + m) % n
+	} else {
+		for k := j; k < d.size; k++ {
+			d.data[(d.front+k-m)%n] = d.data[(d.front+k)%n]
+		}
+		for k := d.size - m; k < d.size; k++ {
+			d.data[(d.front+k)%n] = zero
+		}
+		d.back = (d.back - m + n) % n
+	}
+	d.size -= m
+	d.stats.Pops += uint64(m)
+	d.maybeShrink()
+}
+
+// Rotate moves n items from the front to the back, or -n items from the
+// back to the front when n is negative. It moves at most half of the items
+// and only adjusts indexes when the deque is full.
+func (d *Deque[T]) Rotate(n int) {
+	if d.size == 0 {
+		return
+	}
+	n %= d.size
+	if n < 0 {
+		n += d.size
+	}
+	if n == 0 {
+		return
+	}
+	if d.seg != nil {
+		d.seg.rotate(n)
+		return
+	}
+	l := len(d.data)
+	if d.size == l {
+		d.front = (d.front + n) % l
+		d.back = d.front
+		return
+	}
+	if d.shared {
+		d.unshare()
+	}
+	var zero T
+	if n <= d.size-n {
+		for ; n > 0; n-- {
+			d.data[d.back] = d.data[d.front]
+			d.data[d.front] = zero
+			d.front = (d.front + 1) % l
+			d.back = (d.back + 1) % l
+		}
+		return
+	}
+	for n = d.size - n; n > 0; n-- {
+		d.front = (d.front - 1 + l) % l
+		d.back = (d.back - 1 + l) % l
+		d.data[d.front] = d.data[d.back]
+		d.data[d.back] = zero
+	}
+}
+
+// Reverse reverses the order of the items in place.
+func (d *Deque[T]) Reverse() {
+	if d.seg != nil {
+		d.seg.reverse()
+		return
+	}
+	if d.shared {
+		d.unshare()
+	}
+	l := len(d.data)
+	for i, j := 0, d.size-1; i < j; i, j = i+1, j-1 {
+		a, b := (d.front+i)%l, (d.front+j)%l
+		d.data[a], d.data[b] = d.data[b], d.data[a]
+	}
+}
+
+// SortFunc sorts the items from front to back in ascending order as
+// determined by cmp, like slices.SortFunc. The ring is sorted in place;
+// the segmented backend sorts a copy and writes it back.
+func (d *Deque[T]) SortFunc(cmp func(a, b T) int) {
+	if d.seg != nil {
+		d.seg.sortFunc(cmp)
+		return
+	}
+	if d.shared {
+		d.unshare()
+	}
+	if d.front+d.size > len(d.data) {
+		// Rotate the backing array so the items start at index 0
+		slices.Reverse(d.data[:d.front])
+		slices.Reverse(d.data[d.front:])
+		slices.Reverse(d.data)
+		d.front = 0
+		d.back = d.size % len(d.data)
+	}
+	slices.SortFunc(d.data[d.front:d.front+d.size], cmp)
+}
+
+// At returns the item i positions from the front. It returns false if i
+// is out of range.
+func (d *Deque[T]) At(i int) (T, bool) {
+	var zero T
+	if i < 0 || i >= d.size {
+		return zero, false
+	}
+	return d.at(i), true
+}
+
+func (d *Deque[T]) at(i int) T {
+	if d.seg != nil {
+		c := d.seg.cursor(i)
+		return *c.ptr()
+	}
+	return d.data[(d.front+i)%len(d.data)]
+}
+
+// BinarySearchFunc searches a deque sorted by cmp for target, like
+// slices.BinarySearchFunc. It returns the index of the first item that is
+// not less than target, and whether that item equals target.
+func (d *Deque[T]) BinarySearchFunc(target T, cmp func(a, b T) int) (int, bool) {
+	i := d.search(func(val T) bool { return cmp(val, target) >= 0 })
+	return i, i < d.size && cmp(d.at(i), target) == 0
+}
+
+// InsertSorted inserts val into a deque sorted by cmp, after any items
+// equal to it, and returns its index.
+func (d *Deque[T]) InsertSorted(val T, cmp func(a, b T) int) int {
+	i := d.search(func(item T) bool { return cmp(item, val) > 0 })
+	d.Insert(i, val)
+	return i
+}
+
+// search returns the first index in [0, Size()) at which pred is true,
+// assuming pred is false and then true from front to back, like
+// sort.Search.
+func (d *Deque[T]) search(pred func(T) bool) int {
+	if d.seg != nil {
+		return d.seg.search(pred)
+	}
+	lo, hi := 0, d.size
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if pred(d.at(mid)) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo
+}
+
 func (d *Deque[T]) Size() int {
 	return d.size
 }
 
+// Stats returns usage counters for the deque.
+func (d *Deque[T]) Stats() Stats {
+	return d.stats
+}
+
 func (d *Deque[T]) Cap() int {
+	if d.seg != nil {
+		return d.seg.cap()
+	}
 	return cap(d.data)
 }
 
@@ -108,10 +432,33 @@ func (d *Deque[T]) IsEmpty() bool {
 	return d.size == 0
 }
 
+// Reserve makes room for at least n items, so the deque does not grow
+// again until it holds more than n. Auto-shrink may still release the
+// space once usage drops.
+func (d *Deque[T]) Reserve(n int) {
+	if d.seg != nil {
+		d.seg.reserve(n)
+		return
+	}
+	if n > len(d.data) {
+		d.realloc(n)
+	}
+}
+
 func (d *Deque[T]) Reset() {
-	var zero T
-	for i := 0; i < d.size; i++ {
-		d.data[(d.front+i)%len(d.data)] = zero
+	if d.seg != nil {
+		d.seg.reset()
+		d.size = 0
+		return
+	}
+	if d.shared {
+		d.data = make([]T, len(d.data))
+		d.shared = false
+	} else {
+		var zero T
+		for i := 0; i < d.size; i++ {
+			d.data[(d.front+i)%len(d.data)] = zero
+		}
 	}
 	d.front = 0
 	d.back = 0
@@ -122,40 +469,64 @@ func (d *Deque[T]) Reset() {
 }
 
 func (d *Deque[T]) grow() {
-	newCap := len(d.data) * 2
-	if newCap == 0 {
-		newCap = 4
-	}
-	newData := make([]T, newCap)
-	for i := 0; i < d.size; i++ {
-		newData[i] = d.data[(d.front+i)%len(d.data)]
-	}
-	d.data = newData
-	d.front = 0
-	d.back = d.size
+	d.realloc(d.opts.nextCap(len(d.data), d.size+1))
 }
 
 func (d *Deque[T]) maybeShrink() {
-	if !d.opts.EnableAutoShrink {
-		return
-	}
-	if cap(d.data) > d.opts.ShrinkThresholdCap &&
-		float64(d.size) < float64(cap(d.data))*d.opts.ShrinkUsageRatio {
+	if d.opts.shouldShrink(d.size, cap(d.data)) {
 		d.shrink()
 	}
 }
 
 func (d *Deque[T]) shrink() {
-	newData := make([]T, d.size)
+	if target := max(d.opts.shrinkTarget(d.size), d.opts.InitialCapacity); target < len(d.data) {
+		d.realloc(target)
+	}
+}
+
+// Snapshot returns a read-only view of the deque. The deque copies its
+// items on the next change instead of writing over the snapshot. With the
+// segmented backend the snapshot copies the items up front.
+func (d *Deque[T]) Snapshot() Snapshot[T] {
+	if d.seg != nil {
+		return Snapshot[T]{data: d.seg.appendTo(make([]T, 0, d.size)), size: d.size}
+	}
+	d.shared = d.shared || d.size > 0
+	return Snapshot[T]{data: d.data, start: d.front, size: d.size}
+}
+
+// unshare gives the deque its own copy of the items held by a Snapshot.
+// Pops only move indices, so only writes into data need to call it.
+func (d *Deque[T]) unshare() {
+	d.moveTo(len(d.data))
+}
+
+// realloc moves the items to the front of a new slice of length newCap.
+func (d *Deque[T]) realloc(newCap int) {
+	oldCap := len(d.data)
+	d.moveTo(newCap)
+	recordResize[T](&d.stats, d.opts.ResizeHooks, oldCap, newCap, d.size)
+	d.opts.resized(newCap)
+}
+
+func (d *Deque[T]) moveTo(newCap int) {
+	newData := make([]T, newCap)
 	for i := 0; i < d.size; i++ {
 		newData[i] = d.data[(d.front+i)%len(d.data)]
 	}
 	d.data = newData
 	d.front = 0
-	d.back = d.size
+	d.back = 0
+	if d.size < newCap {
+		d.back = d.size
+	}
+	d.shared = false
 }
 
 func (d *Deque[T]) ItemsCopy() []T {
+	if d.seg != nil {
+		return d.seg.appendTo(make([]T, 0, d.size))
+	}
 	cp := make([]T, d.size)
 	for i := 0; i < d.size; i++ {
 		cp[i] = d.data[(d.front+i)%len(d.data)]
@@ -1,6 +1,10 @@
 package typed
 
 import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
 	"testing"
 )
 
@@ -337,6 +341,508 @@ func TestDeque_Options(t *testing.T) {
 	}
 }
 
+// TestDeque_ShrinkThenPush verifies that pushes after an exact-size shrink keep FIFO order
+func TestDeque_ShrinkThenPush(t *testing.T) {
+	d := NewDeque[int](WithDequeLimitOptions(WithShrinkThresholdCap(4)))
+
+	next, want := 0, 0
+	for round := 0; round < 200; round++ {
+		n := (round * 7919) % 23
+		if round%2 == 0 {
+			for i := 0; i < n; i++ {
+				d.PushBack(next)
+				next++
+			}
+			continue
+		}
+		for i := 0; i < n; i++ {
+			val, ok := d.PopFront()
+			if !ok {
+				break
+			}
+			if val != want {
+				t.Fatalf("round %d: expected %d, got %d", round, want, val)
+			}
+			want++
+		}
+	}
+}
+
+func TestDeque_Segmented(t *testing.T) {
+	// Size 1 keeps every item in its own block
+	for _, size := range []int{1, 2, 4} {
+		t.Run(fmt.Sprintf("size %d", size), func(t *testing.T) {
+			d := NewDeque[int](WithDequeSegmentSize(size))
+			var model []int
+
+			for i := 0; i < 2000; i++ {
+				switch (i * 7919) % 5 {
+				case 0, 1:
+					d.PushBack(i)
+					model = append(model, i)
+				case 2:
+					d.PushFront(i)
+					model = append([]int{i}, model...)
+				case 3:
+					val, ok := d.PopFront()
+					if len(model) == 0 {
+						if ok {
+							t.Fatalf("op %d: popFront expected to fail but succeeded with %v", i, val)
+						}
+						continue
+					}
+					if !ok || val != model[0] {
+						t.Fatalf("op %d: popFront expected %d, got %v (ok=%v)", i, model[0], val, ok)
+					}
+					model = model[1:]
+				case 4:
+					val, ok := d.PopBack()
+					if len(model) == 0 {
+						if ok {
+							t.Fatalf("op %d: popBack expected to fail but succeeded with %v", i, val)
+						}
+						continue
+					}
+					if !ok || val != model[len(model)-1] {
+						t.Fatalf("op %d: popBack expected %d, got %v (ok=%v)", i, model[len(model)-1], val, ok)
+					}
+					model = model[:len(model)-1]
+				}
+				if d.Size() != len(model) {
+					t.Fatalf("op %d: size expected %d, got %d", i, len(model), d.Size())
+				}
+				if len(model) > 0 {
+					front, _ := d.PeekFront()
+					back, _ := d.PeekBack()
+					if front != model[0] || back != model[len(model)-1] {
+						t.Fatalf("op %d: peek expected %d/%d, got %d/%d", i, model[0], model[len(model)-1], front, back)
+					}
+				}
+			}
+
+			items := d.ItemsCopy()
+			if len(items) != len(model) {
+				t.Fatalf("itemsCopy expected length %d, got %d", len(model), len(items))
+			}
+			for i, v := range model {
+				if items[i] != v {
+					t.Errorf("itemsCopy[%d] expected %d, got %d", i, v, items[i])
+				}
+			}
+
+			for i := 0; i < 100; i++ {
+				d.PushFront(i)
+			}
+			grown := d.Cap()
+			for !d.IsEmpty() {
+				d.PopBack()
+			}
+			if d.Cap() >= grown || d.Cap() > (1+maxFreeSegments)*size {
+				t.Errorf("expected empty blocks to be released, cap went from %d to %d", grown, d.Cap())
+			}
+
+			d.PushBack(1)
+			d.Reset()
+			if !d.IsEmpty() {
+				t.Errorf("expected deque to be empty after reset")
+			}
+			d.PushFront(7)
+			if val, ok := d.PopBack(); !ok || val != 7 {
+				t.Errorf("expected 7 after reset, got %v (ok=%v)", val, ok)
+			}
+		})
+	}
+}
+
+func TestDeque_Growth(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []GrowthOption
+		reserve  int
+		push     int
+		expected int
+	}{
+		{"initial capacity", []GrowthOption{WithInitialCapacity(16)}, 0, 16, 16},
+		{"growth factor", []GrowthOption{WithInitialCapacity(16), WithGrowthFactor(1.5)}, 0, 17, 24},
+		{"max growth step", []GrowthOption{WithInitialCapacity(16), WithMaxGrowthStep(4)}, 0, 21, 24},
+		{"reserve", nil, 100, 100, 100},
+		{"reserve below capacity", []GrowthOption{WithInitialCapacity(16)}, 8, 10, 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDeque[int](WithDequeGrowthOptions(tt.opts...))
+			if tt.reserve > 0 {
+				d.Reserve(tt.reserve)
+			}
+			for i := 0; i < tt.push; i++ {
+				d.PushBack(i)
+			}
+			if got := d.Cap(); got != tt.expected {
+				t.Errorf("expected capacity %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeque_InsertRemove(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "insert at both ends and the middle",
+			steps: []step{
+				{"insert", [2]int{0, 2}, nil},
+				{"insert", [2]int{0, 0}, nil},
+				{"insert", [2]int{2, 3}, nil},
+				{"insert", [2]int{1, 1}, nil},
+				{"items", nil, []int{0, 1, 2, 3}},
+			},
+		},
+		{
+			name: "remove at",
+			steps: []step{
+				{"pushMany", 6, nil},
+				{"removeAt", 1, 1},
+				{"removeAt", 3, 4},
+				{"removeAt", 4, nil},
+				{"removeAt", -1, nil},
+				{"items", nil, []int{0, 2, 3, 5}},
+				{"removeAt", 0, 0},
+				{"removeAt", 2, 5},
+				{"items", nil, []int{2, 3}},
+			},
+		},
+		{
+			name: "remove range",
+			steps: []step{
+				{"pushMany", 10, nil},
+				{"removeRange", [2]int{1, 3}, nil},
+				{"items", nil, []int{0, 3, 4, 5, 6, 7, 8, 9}},
+				{"removeRange", [2]int{4, 7}, nil},
+				{"items", nil, []int{0, 3, 4, 5, 9}},
+				{"removeRange", [2]int{2, 2}, nil},
+				{"removeRange", [2]int{0, 5}, nil},
+				{"items", nil, []int{}},
+			},
+		},
+	}
+
+	backends := map[string][]DequeOption{
+		"ring":      {WithDequeGrowthOptions(WithInitialCapacity(4))},
+		"segmented": {WithDequeSegmentSize(2)},
+	}
+	for backend, opts := range backends {
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				d := NewDeque[int](opts...)
+
+				for i, step := range tt.steps {
+					switch step.op {
+					case "pushMany":
+						for j := 0; j < step.value.(int); j++ {
+							d.PushBack(j)
+						}
+					case "insert":
+						args := step.value.([2]int)
+						d.Insert(args[0], args[1])
+					case "removeAt":
+						val, ok := d.RemoveAt(step.value.(int))
+						if step.expected == nil {
+							if ok {
+								t.Errorf("step %d: removeAt expected to fail, got %v", i, val)
+							}
+						} else if !ok || val != step.expected.(int) {
+							t.Errorf("step %d: removeAt expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+						}
+					case "removeRange":
+						args := step.value.([2]int)
+						d.RemoveRange(args[0], args[1])
+					case "items":
+						if got := d.ItemsCopy(); !reflect.DeepEqual(got, step.expected.([]int)) {
+							t.Errorf("step %d: items expected %v, got %v", i, step.expected, got)
+						}
+					default:
+						t.Fatalf("step %d: unknown op %s", i, step.op)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestDeque_InsertRemoveModel(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	backends := map[string][]DequeOption{
+		"ring":      {WithDequeLimitOptions(WithShrinkThresholdCap(4))},
+		"segmented": {WithDequeSegmentSize(3)},
+	}
+	for backend, opts := range backends {
+		t.Run(backend, func(t *testing.T) {
+			d := NewDeque[int](opts...)
+			var model []int
+			// Keep several snapshots alive at once, including empty ones
+			snaps := make([]Snapshot[int], 8)
+			snapModels := make([][]int, 8)
+
+			for op := 0; op < 5000; op++ {
+				switch r.Intn(6) {
+				case 0, 1:
+					i := r.Intn(len(model) + 1)
+					d.Insert(i, op)
+					model = append(model[:i], append([]int{op}, model[i:]...)...)
+				case 2:
+					d.PushFront(op)
+					model = append([]int{op}, model...)
+				case 3:
+					i := r.Intn(len(model) + 1)
+					val, ok := d.RemoveAt(i)
+					if i == len(model) {
+						if ok {
+							t.Fatalf("op %d: removeAt(%d) expected to fail, got %v", op, i, val)
+						}
+						continue
+					}
+					if !ok || val != model[i] {
+						t.Fatalf("op %d: removeAt(%d) expected %d, got %v (ok=%v)", op, i, model[i], val, ok)
+					}
+					model = append(model[:i], model[i+1:]...)
+				case 4:
+					i := r.Intn(len(model) + 1)
+					j := i + r.Intn(min(4, len(model)-i)+1)
+					d.RemoveRange(i, j)
+					model = append(model[:i], model[j:]...)
+				case 5:
+					k := r.Intn(len(snaps))
+					snaps[k] = d.Snapshot()
+					snapModels[k] = append([]int(nil), model...)
+				}
+				if got := d.ItemsCopy(); !reflect.DeepEqual(got, model) && !(len(got) == 0 && len(model) == 0) {
+					t.Fatalf("op %d: expected %v, got %v", op, model, got)
+				}
+				for k, snap := range snaps {
+					if got := snap.Items(); len(got) != len(snapModels[k]) || (len(got) > 0 && !reflect.DeepEqual(got, snapModels[k])) {
+						t.Fatalf("op %d: snapshot %d changed from %v to %v", op, k, snapModels[k], got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDeque_InsertOutOfRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	NewDeque[int]().Insert(1, 0)
+}
+
+func TestDeque_RotateReverseSort(t *testing.T) {
+	type step struct {
+		op       string
+		value    int
+		expected []int
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "rotate",
+			steps: []step{
+				{"rotate", 2, []int{2, 3, 4, 0, 1}},
+				{"rotate", -1, []int{1, 2, 3, 4, 0}},
+				{"rotate", 4, []int{0, 1, 2, 3, 4}},
+				{"rotate", 12, []int{2, 3, 4, 0, 1}},
+				{"rotate", -7, []int{0, 1, 2, 3, 4}},
+				{"rotate", 0, []int{0, 1, 2, 3, 4}},
+			},
+		},
+		{
+			name: "reverse",
+			steps: []step{
+				{"reverse", 0, []int{4, 3, 2, 1, 0}},
+				{"rotate", 1, []int{3, 2, 1, 0, 4}},
+				{"reverse", 0, []int{4, 0, 1, 2, 3}},
+			},
+		},
+		{
+			name: "sort",
+			steps: []step{
+				{"reverse", 0, []int{4, 3, 2, 1, 0}},
+				{"rotate", 3, []int{1, 0, 4, 3, 2}},
+				{"sort", 0, []int{0, 1, 2, 3, 4}},
+				{"rotate", 2, []int{2, 3, 4, 0, 1}},
+				{"sort", 0, []int{0, 1, 2, 3, 4}},
+			},
+		},
+	}
+
+	backends := map[string][]DequeOption{
+		"ring":      {WithDequeGrowthOptions(WithInitialCapacity(8))},
+		"full ring": {WithDequeGrowthOptions(WithInitialCapacity(5))},
+		"segmented": {WithDequeSegmentSize(2)},
+	}
+	for backend, opts := range backends {
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				d := NewDeque[int](opts...)
+				// Start the items in the middle of the ring
+				for i := 0; i < 3; i++ {
+					d.PushBack(-1)
+					d.PopFront()
+				}
+				for i := 0; i < 5; i++ {
+					d.PushBack(i)
+				}
+
+				for i, step := range tt.steps {
+					switch step.op {
+					case "rotate":
+						d.Rotate(step.value)
+					case "reverse":
+						d.Reverse()
+					case "sort":
+						d.SortFunc(func(a, b int) int { return a - b })
+					default:
+						t.Fatalf("step %d: unknown op %s", i, step.op)
+					}
+					if got := d.ItemsCopy(); !reflect.DeepEqual(got, step.expected) {
+						t.Errorf("step %d: %s expected %v, got %v", i, step.op, step.expected, got)
+					}
+					front, _ := d.PeekFront()
+					back, _ := d.PeekBack()
+					if front != step.expected[0] || back != step.expected[4] {
+						t.Errorf("step %d: peek expected %d/%d, got %d/%d", i, step.expected[0], step.expected[4], front, back)
+					}
+				}
+
+				d.PushBack(5)
+				d.PushFront(-1)
+				if got := d.Size(); got != 7 {
+					t.Errorf("expected size 7 after pushes, got %d", got)
+				}
+			})
+		}
+	}
+}
+
+func TestDeque_RotateFullNoCopy(t *testing.T) {
+	d := NewDeque[int](WithDequeGrowthOptions(WithInitialCapacity(1024)))
+	for i := 0; i < 1024; i++ {
+		d.PushBack(i)
+	}
+	snap := d.Snapshot()
+	d.Rotate(500)
+	if !d.shared {
+		t.Error("expected rotating a full deque not to copy its items")
+	}
+	if val, _ := d.PeekFront(); val != 500 || snap.At(0) != 0 {
+		t.Errorf("expected front 500 and snapshot front 0, got %d and %d", val, snap.At(0))
+	}
+}
+
+func TestDeque_BinarySearch(t *testing.T) {
+	type event struct {
+		at int
+		id string
+	}
+	byTime := func(a, b event) int { return a.at - b.at }
+
+	backends := map[string][]DequeOption{
+		"ring":      {WithDequeGrowthOptions(WithInitialCapacity(4))},
+		"segmented": {WithDequeSegmentSize(2)},
+	}
+	for backend, opts := range backends {
+		t.Run(backend, func(t *testing.T) {
+			d := NewDeque[event](opts...)
+			// Wrap the ring before filling it
+			d.PushBack(event{})
+			d.PopFront()
+			for _, e := range []event{{30, "c"}, {10, "a"}, {20, "b"}, {20, "b2"}, {40, "d"}, {10, "a2"}} {
+				d.InsertSorted(e, byTime)
+			}
+			var ids []string
+			for _, e := range d.ItemsCopy() {
+				ids = append(ids, e.id)
+			}
+			if want := []string{"a", "a2", "b", "b2", "c", "d"}; !reflect.DeepEqual(ids, want) {
+				t.Fatalf("expected %v, got %v", want, ids)
+			}
+
+			tests := []struct {
+				at    int
+				index int
+				found bool
+			}{
+				{5, 0, false},
+				{10, 0, true},
+				{20, 2, true},
+				{25, 4, false},
+				{40, 5, true},
+				{50, 6, false},
+			}
+			for _, tt := range tests {
+				i, found := d.BinarySearchFunc(event{at: tt.at}, byTime)
+				if i != tt.index || found != tt.found {
+					t.Errorf("search %d: expected (%d, %v), got (%d, %v)", tt.at, tt.index, tt.found, i, found)
+				}
+			}
+
+			// First event after 20
+			i := d.InsertSorted(event{20, "b3"}, byTime)
+			if e, ok := d.At(i + 1); i != 4 || !ok || e.id != "c" {
+				t.Errorf("expected b3 at 4 followed by c, got %d and %v", i, e)
+			}
+			if _, ok := d.At(d.Size()); ok {
+				t.Error("expected At past the end to fail")
+			}
+		})
+	}
+}
+
+func TestDeque_BinarySearchSegmented(t *testing.T) {
+	const n, block = 10000, 16
+	d := NewDeque[int](WithDequeSegmentSize(block))
+	sorted := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		d.PushBack(2 * i)
+		sorted = append(sorted, 2*i)
+	}
+	d.PopFront() // start part way into the first block
+	sorted = sorted[1:]
+
+	for _, target := range []int{-1, 0, 1, 2, 777, 10000, 19997, 19998, 20000} {
+		i, found := d.BinarySearchFunc(target, func(a, b int) int { return a - b })
+		wi, wfound := slices.BinarySearch(sorted, target)
+		if i != wi || found != wfound {
+			t.Errorf("search %d: expected (%d, %v), got (%d, %v)", target, wi, wfound, i, found)
+		}
+	}
+}
+
+func BenchmarkDeque_BinarySearchSegmented(b *testing.B) {
+	d := NewDeque[int](WithDequeSegmentSize(64))
+	for i := 0; i < 1<<16; i++ {
+		d.PushBack(i)
+	}
+	cmp := func(a, b int) int { return a - b }
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.BinarySearchFunc(i&(1<<16-1), cmp)
+	}
+}
+
 // Example of using Deque
 func ExampleDeque() {
 	// Create a new deque of integers
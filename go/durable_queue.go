@@ -0,0 +1,511 @@
+package typed
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	durablePush byte = 1
+	durableAck  byte = 2
+
+	durableSegmentExt = ".seg"
+)
+
+var (
+	ErrDurableQueueClosed = errors.New("typed: durable queue is closed")
+	ErrNotInFlight        = errors.New("typed: id is not in flight")
+)
+
+// SyncPolicy controls when a DurableQueue calls fsync on its log.
+type SyncPolicy int
+
+const (
+	SyncAlways   SyncPolicy = iota // fsync after every record
+	SyncInterval                   // fsync on a write once SyncEvery has passed since the last one
+	SyncNever                      // leave flushing to the OS, Sync and Close still fsync
+)
+
+type DurableQueueOptions struct {
+	SyncPolicy   SyncPolicy
+	SyncEvery    time.Duration // Minimum time between fsyncs for SyncInterval
+	SegmentBytes int64         // Size at which the active segment is rotated
+}
+
+type DurableQueueOption func(*DurableQueueOptions)
+
+func defaultDurableQueueOptions() DurableQueueOptions {
+	return DurableQueueOptions{
+		SyncPolicy:   SyncAlways,
+		SyncEvery:    100 * time.Millisecond,
+		SegmentBytes: 16 << 20,
+	}
+}
+
+func WithSyncPolicy(policy SyncPolicy) DurableQueueOption {
+	return func(o *DurableQueueOptions) {
+		o.SyncPolicy = policy
+	}
+}
+
+// WithSyncEvery selects SyncInterval with the given period.
+func WithSyncEvery(d time.Duration) DurableQueueOption {
+	if d <= 0 {
+		panic("Sync interval must be greater than 0")
+	}
+	return func(o *DurableQueueOptions) {
+		o.SyncPolicy = SyncInterval
+		o.SyncEvery = d
+	}
+}
+
+func WithSegmentBytes(n int64) DurableQueueOption {
+	if n <= 0 {
+		panic("Segment bytes must be greater than 0")
+	}
+	return func(o *DurableQueueOptions) {
+		o.SegmentBytes = n
+	}
+}
+
+type durableEntry[T any] struct {
+	id  uint64
+	seg uint64
+	val T
+}
+
+// DurableQueue is a FIFO queue whose contents survive process restarts.
+// Every Push and Ack is appended to a log of segment files in a directory,
+// and OpenDurableQueue replays that log.
+//
+// Pop hands out an item together with an id and keeps it in flight until
+// it is acknowledged with Ack. Items that were popped but never acked are
+// delivered again after the queue is reopened, so consumers see each item
+// at least once.
+//
+// A DurableQueue is not safe for concurrent use.
+type DurableQueue[T any] struct {
+	dir   string
+	codec Codec[T]
+	opts  DurableQueueOptions
+
+	pending  *Queue[durableEntry[T]]
+	inFlight map[uint64]durableEntry[T]
+	live     map[uint64]int // segment number -> pushes not yet acked
+	segs     []uint64       // segment numbers on disk, oldest first
+	nextID   uint64
+
+	active     *os.File
+	activeSeg  uint64
+	activeSize int64 // end of the last complete record in active
+	torn       bool  // active holds bytes of a failed write past activeSize
+	lastSync   time.Time
+	buf        []byte
+	closed     bool
+}
+
+// OpenDurableQueue opens the queue stored in dir, creating the directory if
+// needed, and replays its log. A record cut short by a crash at the end of
+// the newest segment is discarded. A nil codec selects JSONCodec.
+func OpenDurableQueue[T any](dir string, codec Codec[T], opts ...DurableQueueOption) (*DurableQueue[T], error) {
+	o := defaultDurableQueueOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if codec == nil {
+		codec = JSONCodec[T]{}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	q := &DurableQueue[T]{
+		dir:      dir,
+		codec:    codec,
+		opts:     o,
+		pending:  NewQueue[durableEntry[T]](),
+		inFlight: make(map[uint64]durableEntry[T]),
+		live:     make(map[uint64]int),
+		nextID:   1,
+	}
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+	if err := q.openActive(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *DurableQueue[T]) segmentPath(seg uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seg, durableSegmentExt))
+}
+
+func (q *DurableQueue[T]) listSegments() ([]uint64, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []uint64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, durableSegmentExt) {
+			continue
+		}
+		seg, err := strconv.ParseUint(strings.TrimSuffix(name, durableSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, seg)
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i] < segs[j] })
+	return segs, nil
+}
+
+func (q *DurableQueue[T]) replay() error {
+	segs, err := q.listSegments()
+	if err != nil {
+		return err
+	}
+
+	pushed := make(map[uint64]durableEntry[T])
+	acked := make(map[uint64]struct{})
+	for i, seg := range segs {
+		path := q.segmentPath(seg)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		off := 0
+		for off < len(data) {
+			rec, n, err := nextRecord(data[off:])
+			if err != nil {
+				if i != len(segs)-1 {
+					return fmt.Errorf("typed: segment %s is corrupt at offset %d", path, off)
+				}
+				if err := os.Truncate(path, int64(off)); err != nil {
+					return err
+				}
+				break
+			}
+			off += n
+			if rec.id >= q.nextID {
+				q.nextID = rec.id + 1
+			}
+			switch rec.kind {
+			case durablePush:
+				if _, ok := pushed[rec.id]; ok {
+					continue // rewritten by a compaction that did not finish
+				}
+				val, err := q.codec.Decode(rec.payload)
+				if err != nil {
+					return fmt.Errorf("typed: decoding record %d in %s: %w", rec.id, path, err)
+				}
+				pushed[rec.id] = durableEntry[T]{id: rec.id, seg: seg, val: val}
+			case durableAck:
+				acked[rec.id] = struct{}{}
+			}
+		}
+	}
+
+	ids := make([]uint64, 0, len(pushed))
+	for id := range pushed {
+		if _, ok := acked[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		e := pushed[id]
+		q.pending.Push(e)
+		q.live[e.seg]++
+	}
+	q.segs = segs
+	return nil
+}
+
+// openActive continues the newest segment, or starts the first one.
+func (q *DurableQueue[T]) openActive() error {
+	if len(q.segs) == 0 {
+		return q.startSegment(1)
+	}
+	seg := q.segs[len(q.segs)-1]
+	f, err := os.OpenFile(q.segmentPath(seg), os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	q.active, q.activeSeg, q.activeSize = f, seg, info.Size()
+	q.lastSync = time.Now()
+	return nil
+}
+
+func (q *DurableQueue[T]) startSegment(seg uint64) error {
+	f, err := os.OpenFile(q.segmentPath(seg), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(q.dir); err != nil {
+		f.Close()
+		return err
+	}
+	q.active, q.activeSeg, q.activeSize = f, seg, 0
+	q.segs = append(q.segs, seg)
+	q.lastSync = time.Now()
+	return nil
+}
+
+func (q *DurableQueue[T]) rotate() error {
+	// A torn record must not be left behind in an older segment, where
+	// Open treats it as corruption.
+	if err := q.repair(); err != nil {
+		return err
+	}
+	if err := q.active.Sync(); err != nil {
+		return err
+	}
+	if err := q.active.Close(); err != nil {
+		return err
+	}
+	if err := q.startSegment(q.activeSeg + 1); err != nil {
+		return err
+	}
+	return q.dropAckedSegments()
+}
+
+// dropAckedSegments deletes the oldest segments while every push in them
+// has been acked. Deleting strictly from the front keeps every surviving
+// ack record next to the push it refers to.
+func (q *DurableQueue[T]) dropAckedSegments() error {
+	for len(q.segs) > 0 && q.segs[0] != q.activeSeg && q.live[q.segs[0]] == 0 {
+		if err := os.Remove(q.segmentPath(q.segs[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(q.live, q.segs[0])
+		q.segs = q.segs[1:]
+	}
+	return nil
+}
+
+// appendActive writes buf after the last complete record of the active
+// segment. A failed write is cut off again, so that replay never stops at
+// a torn record with good records after it.
+func (q *DurableQueue[T]) appendActive(buf []byte) error {
+	if err := q.repair(); err != nil {
+		return err
+	}
+	if _, err := q.active.WriteAt(buf, q.activeSize); err != nil {
+		q.torn = true
+		q.repair()
+		return err
+	}
+	q.activeSize += int64(len(buf))
+	return nil
+}
+
+// repair truncates the active segment back to its last complete record
+// after a failed write.
+func (q *DurableQueue[T]) repair() error {
+	if !q.torn {
+		return nil
+	}
+	if err := q.active.Truncate(q.activeSize); err != nil {
+		return err
+	}
+	q.torn = false
+	return nil
+}
+
+func (q *DurableQueue[T]) write(kind byte, id uint64, payload []byte) error {
+	if q.activeSize >= q.opts.SegmentBytes {
+		if err := q.rotate(); err != nil {
+			return err
+		}
+	}
+	q.buf = appendRecord(q.buf[:0], kind, id, payload)
+	if err := q.appendActive(q.buf); err != nil {
+		return err
+	}
+	switch q.opts.SyncPolicy {
+	case SyncAlways:
+		return q.active.Sync()
+	case SyncInterval:
+		if time.Since(q.lastSync) >= q.opts.SyncEvery {
+			q.lastSync = time.Now()
+			return q.active.Sync()
+		}
+	}
+	return nil
+}
+
+// Push appends val to the log and then to the queue.
+func (q *DurableQueue[T]) Push(val T) error {
+	if q.closed {
+		return ErrDurableQueueClosed
+	}
+	payload, err := q.codec.Encode(val)
+	if err != nil {
+		return err
+	}
+	id := q.nextID
+	if err := q.write(durablePush, id, payload); err != nil {
+		return err
+	}
+	q.nextID++
+	q.pending.Push(durableEntry[T]{id: id, seg: q.activeSeg, val: val})
+	q.live[q.activeSeg]++
+	return nil
+}
+
+// Pop removes the oldest item and keeps it in flight until Ack is called
+// with the returned id.
+func (q *DurableQueue[T]) Pop() (uint64, T, bool) {
+	var zero T
+	if q.closed {
+		return 0, zero, false
+	}
+	e, ok := q.pending.Pop()
+	if !ok {
+		return 0, zero, false
+	}
+	q.inFlight[e.id] = e
+	return e.id, e.val, true
+}
+
+func (q *DurableQueue[T]) Peek() (T, bool) {
+	e, ok := q.pending.Peek()
+	return e.val, ok
+}
+
+// Ack records that the in-flight item with the given id has been handled,
+// so it is not delivered again.
+func (q *DurableQueue[T]) Ack(id uint64) error {
+	if q.closed {
+		return ErrDurableQueueClosed
+	}
+	e, ok := q.inFlight[id]
+	if !ok {
+		return ErrNotInFlight
+	}
+	if err := q.write(durableAck, id, nil); err != nil {
+		return err
+	}
+	delete(q.inFlight, id)
+	q.live[e.seg]--
+	return q.dropAckedSegments()
+}
+
+// Size returns the number of items waiting to be popped.
+func (q *DurableQueue[T]) Size() int {
+	return q.pending.Size()
+}
+
+func (q *DurableQueue[T]) IsEmpty() bool {
+	return q.pending.IsEmpty()
+}
+
+// InFlight returns the number of popped items that have not been acked.
+func (q *DurableQueue[T]) InFlight() int {
+	return len(q.inFlight)
+}
+
+// Sync flushes the active segment to stable storage.
+func (q *DurableQueue[T]) Sync() error {
+	if q.closed {
+		return ErrDurableQueueClosed
+	}
+	q.lastSync = time.Now()
+	return q.active.Sync()
+}
+
+// Compact rewrites every unacked item into a fresh segment and deletes all
+// older segments. If the process stops part way through, the next Open
+// still sees each item exactly once.
+func (q *DurableQueue[T]) Compact() error {
+	if q.closed {
+		return ErrDurableQueueClosed
+	}
+	entries := make([]durableEntry[T], 0, len(q.inFlight)+q.pending.Size())
+	for _, e := range q.inFlight {
+		entries = append(entries, e)
+	}
+	inFlight := len(entries)
+	for !q.pending.IsEmpty() {
+		e, _ := q.pending.Pop()
+		entries = append(entries, e)
+	}
+	sort.Slice(entries[:inFlight], func(i, j int) bool { return entries[i].id < entries[j].id })
+
+	// Put the in-memory state back first, so a failed write leaves the
+	// queue exactly as it was.
+	for _, e := range entries[inFlight:] {
+		q.pending.Push(e)
+	}
+
+	if err := q.rotate(); err != nil {
+		return err
+	}
+	seg := q.activeSeg
+	for _, e := range entries {
+		payload, err := q.codec.Encode(e.val)
+		if err != nil {
+			return err
+		}
+		q.buf = appendRecord(q.buf[:0], durablePush, e.id, payload)
+		if err := q.appendActive(q.buf); err != nil {
+			return err
+		}
+	}
+	if err := q.active.Sync(); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entries[i].seg = seg
+	}
+	for _, e := range entries[:inFlight] {
+		q.inFlight[e.id] = e
+	}
+	q.pending.Reset()
+	for _, e := range entries[inFlight:] {
+		q.pending.Push(e)
+	}
+	for s := range q.live {
+		q.live[s] = 0
+	}
+	q.live[seg] = len(entries)
+	return q.dropAckedSegments()
+}
+
+// Close syncs and closes the log. Items still in flight are delivered
+// again when the queue is reopened.
+func (q *DurableQueue[T]) Close() error {
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	if err := q.active.Sync(); err != nil {
+		q.active.Close()
+		return err
+	}
+	return q.active.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,545 @@
+package typed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestDurableQueue(t *testing.T, dir string, opts ...DurableQueueOption) *DurableQueue[int] {
+	t.Helper()
+	q, err := OpenDurableQueue[int](dir, nil, opts...)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return q
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+durableSegmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestDurableQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "basic operations",
+			steps: []step{
+				{"isEmpty", nil, true},
+				{"pop", nil, false},
+				{"push", 10, nil},
+				{"push", 20, nil},
+				{"peek", nil, 10},
+				{"size", nil, 2},
+				{"pop", nil, 10},
+				{"inFlight", nil, 1},
+				{"ackLast", nil, nil},
+				{"inFlight", nil, 0},
+				{"pop", nil, 20},
+				{"isEmpty", nil, true},
+			},
+		},
+		{
+			name: "reopen keeps pending items",
+			steps: []step{
+				{"push", 1, nil},
+				{"push", 2, nil},
+				{"push", 3, nil},
+				{"reopen", nil, nil},
+				{"size", nil, 3},
+				{"pop", nil, 1},
+				{"pop", nil, 2},
+				{"pop", nil, 3},
+			},
+		},
+		{
+			name: "unacked items are redelivered after reopen",
+			steps: []step{
+				{"push", 1, nil},
+				{"push", 2, nil},
+				{"push", 3, nil},
+				{"pop", nil, 1},
+				{"ackLast", nil, nil},
+				{"pop", nil, 2},
+				{"reopen", nil, nil},
+				{"size", nil, 2},
+				{"inFlight", nil, 0},
+				{"pop", nil, 2},
+				{"pop", nil, 3},
+			},
+		},
+		{
+			name: "ack of unknown id fails",
+			steps: []step{
+				{"ack", uint64(42), ErrNotInFlight},
+				{"push", 1, nil},
+				{"pop", nil, 1},
+				{"ackLast", nil, nil},
+				{"ackLast", nil, ErrNotInFlight},
+			},
+		},
+		{
+			name: "closed queue rejects operations",
+			steps: []step{
+				{"push", 1, nil},
+				{"close", nil, nil},
+				{"pushErr", 2, ErrDurableQueueClosed},
+				{"pop", nil, false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			q := openTestDurableQueue(t, dir)
+			defer func() { q.Close() }()
+			var lastID uint64
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					if err := q.Push(step.value.(int)); err != nil {
+						t.Fatalf("step %d: push: %v", i, err)
+					}
+				case "pushErr":
+					if err := q.Push(step.value.(int)); err != step.expected {
+						t.Errorf("step %d: push expected %v, got %v", i, step.expected, err)
+					}
+				case "pop":
+					id, val, ok := q.Pop()
+					if step.expected != false {
+						if !ok || val != step.expected.(int) {
+							t.Errorf("step %d: pop expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+						}
+						lastID = id
+					} else if ok {
+						t.Errorf("step %d: pop expected to fail but succeeded with %v", i, val)
+					}
+				case "peek":
+					val, ok := q.Peek()
+					if !ok || val != step.expected.(int) {
+						t.Errorf("step %d: peek expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+					}
+				case "ack":
+					if err := q.Ack(step.value.(uint64)); err != step.expected {
+						t.Errorf("step %d: ack expected %v, got %v", i, step.expected, err)
+					}
+				case "ackLast":
+					err := q.Ack(lastID)
+					if step.expected == nil && err != nil || step.expected != nil && err != step.expected {
+						t.Errorf("step %d: ack expected %v, got %v", i, step.expected, err)
+					}
+				case "size":
+					if got := q.Size(); got != step.expected.(int) {
+						t.Errorf("step %d: size expected %v, got %v", i, step.expected, got)
+					}
+				case "inFlight":
+					if got := q.InFlight(); got != step.expected.(int) {
+						t.Errorf("step %d: inFlight expected %v, got %v", i, step.expected, got)
+					}
+				case "isEmpty":
+					if got := q.IsEmpty(); got != step.expected.(bool) {
+						t.Errorf("step %d: isEmpty expected %v, got %v", i, step.expected, got)
+					}
+				case "reopen":
+					if err := q.Close(); err != nil {
+						t.Fatalf("step %d: close: %v", i, err)
+					}
+					q = openTestDurableQueue(t, dir)
+				case "close":
+					if err := q.Close(); err != nil {
+						t.Fatalf("step %d: close: %v", i, err)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+// TestDurableQueue_TornWrites simulates a crash in the middle of appending
+// a record by cutting the newest segment short or corrupting its tail.
+func TestDurableQueue_TornWrites(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(t *testing.T, path string, size int64)
+		want   int
+	}{
+		{
+			name: "record cut short",
+			damage: func(t *testing.T, path string, size int64) {
+				if err := os.Truncate(path, size-3); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: 4,
+		},
+		{
+			name: "only part of a header written",
+			damage: func(t *testing.T, path string, size int64) {
+				f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if _, err := f.Write([]byte{9, 0, 0}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: 5,
+		},
+		{
+			name: "checksum mismatch in last record",
+			damage: func(t *testing.T, path string, size int64) {
+				f, err := os.OpenFile(path, os.O_WRONLY, 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if _, err := f.WriteAt([]byte{0xff}, size-1); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			q := openTestDurableQueue(t, dir)
+			for i := 1; i <= 5; i++ {
+				if err := q.Push(i); err != nil {
+					t.Fatal(err)
+				}
+			}
+			q.Close()
+
+			files := segmentFiles(t, dir)
+			if len(files) != 1 {
+				t.Fatalf("expected 1 segment, got %d", len(files))
+			}
+			info, err := os.Stat(files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.damage(t, files[0], info.Size())
+
+			q = openTestDurableQueue(t, dir)
+			want := tt.want
+			if q.Size() != want {
+				t.Fatalf("expected %d items after recovery, got %d", want, q.Size())
+			}
+
+			// The log must accept new records right after the recovered ones.
+			if err := q.Push(100); err != nil {
+				t.Fatal(err)
+			}
+			q.Close()
+			q = openTestDurableQueue(t, dir)
+			for i := 1; i <= want; i++ {
+				if _, val, ok := q.Pop(); !ok || val != i {
+					t.Fatalf("expected %d, got %v (ok=%v)", i, val, ok)
+				}
+			}
+			if _, val, ok := q.Pop(); !ok || val != 100 {
+				t.Fatalf("expected 100, got %v (ok=%v)", val, ok)
+			}
+			q.Close()
+		})
+	}
+}
+
+func TestDurableQueue_FailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestDurableQueue(t, dir, WithSegmentBytes(64))
+	if err := q.Push(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bytes of a torn write past the last record are overwritten by the
+	// next record instead of being followed by it
+	path := segmentFiles(t, dir)[0]
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{1, 2, 3, 4, 5, 6}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := q.Push(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// A write that fails is reported and leaves nothing behind
+	w := q.active
+	r, err := os.Open(q.segmentPath(q.activeSeg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.active = r
+	if err := q.Push(3); err == nil {
+		t.Fatal("expected push to fail on a read-only segment")
+	}
+	r.Close()
+	q.active = w
+
+	// Later records, including ones in newer segments, survive a reopen
+	for i := 4; i <= 8; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.Close()
+
+	q = openTestDurableQueue(t, dir)
+	defer q.Close()
+	for _, want := range []int{1, 2, 4, 5, 6, 7, 8} {
+		if _, val, ok := q.Pop(); !ok || val != want {
+			t.Fatalf("expected %d, got %v (ok=%v)", want, val, ok)
+		}
+	}
+	if !q.IsEmpty() {
+		t.Errorf("expected queue to be empty, got %d items", q.Size())
+	}
+}
+
+func TestDurableQueue_CorruptOlderSegment(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestDurableQueue(t, dir, WithSegmentBytes(32))
+	for i := 0; i < 10; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.Close()
+
+	files := segmentFiles(t, dir)
+	if len(files) < 2 {
+		t.Fatalf("expected several segments, got %d", len(files))
+	}
+	if err := os.Truncate(files[0], 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDurableQueue[int](dir, nil); err == nil {
+		t.Fatal("expected an error for a corrupt segment that is not the newest")
+	}
+}
+
+func TestDurableQueue_RotationAndCompaction(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestDurableQueue(t, dir, WithSegmentBytes(64), WithSyncPolicy(SyncNever))
+	for i := 0; i < 50; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(segmentFiles(t, dir)); n < 5 {
+		t.Fatalf("expected segments to rotate, got %d files", n)
+	}
+
+	// Once every push in a segment is acked, the segment is deleted.
+	for i := 0; i < 50; i++ {
+		id, _, _ := q.Pop()
+		if err := q.Ack(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(segmentFiles(t, dir)); n != 1 {
+		t.Errorf("expected only the active segment to remain, got %d files", n)
+	}
+	q.Close()
+
+	q = openTestDurableQueue(t, dir, WithSegmentBytes(64))
+	if !q.IsEmpty() {
+		t.Fatalf("expected queue to be empty after reopen, size is %d", q.Size())
+	}
+	q.Close()
+
+	// One unacked item at the front pins every segment until Compact.
+	dir = t.TempDir()
+	q = openTestDurableQueue(t, dir, WithSegmentBytes(64), WithSyncPolicy(SyncNever))
+	for i := 0; i < 50; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	firstID, _, _ := q.Pop()
+	for i := 1; i < 45; i++ {
+		id, _, _ := q.Pop()
+		if err := q.Ack(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := len(segmentFiles(t, dir))
+	if err := q.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if after := len(segmentFiles(t, dir)); after != 1 || after >= before {
+		t.Errorf("expected compaction to leave 1 segment, had %d, now %d", before, after)
+	}
+	if q.Size() != 5 || q.InFlight() != 1 {
+		t.Errorf("expected 5 pending and 1 in flight, got %d and %d", q.Size(), q.InFlight())
+	}
+	if err := q.Ack(firstID); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(50); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	q = openTestDurableQueue(t, dir)
+	defer q.Close()
+	for _, want := range []int{45, 46, 47, 48, 49, 50} {
+		if _, val, ok := q.Pop(); !ok || val != want {
+			t.Fatalf("expected %d, got %v (ok=%v)", want, val, ok)
+		}
+	}
+	if !q.IsEmpty() {
+		t.Errorf("expected queue to be empty, size is %d", q.Size())
+	}
+}
+
+// TestDurableQueue_InterruptedCompaction reopens a log in which a compacted
+// segment was written but the segments it replaces were never deleted.
+func TestDurableQueue_InterruptedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestDurableQueue(t, dir)
+	for i := 0; i < 4; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.Close()
+	orig := segmentFiles(t, dir)[0]
+	data, err := os.ReadFile(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q = openTestDurableQueue(t, dir)
+	id, _, _ := q.Pop()
+	if err := q.Ack(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	// Restore the original segment as if deleting it had not happened.
+	if err := os.WriteFile(orig, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	q = openTestDurableQueue(t, dir)
+	defer q.Close()
+	if q.Size() != 4 {
+		// The restored segment predates the ack of item 0, so item 0 comes
+		// back, but nothing written by the compaction is duplicated.
+		t.Fatalf("expected 4 items, got %d", q.Size())
+	}
+	for i := 0; i < 4; i++ {
+		if _, val, ok := q.Pop(); !ok || val != i {
+			t.Fatalf("expected %d, got %v (ok=%v)", i, val, ok)
+		}
+	}
+}
+
+func TestDurableQueue_SyncPolicies(t *testing.T) {
+	for _, opt := range []DurableQueueOption{
+		WithSyncPolicy(SyncAlways),
+		WithSyncPolicy(SyncNever),
+		WithSyncEvery(time.Millisecond),
+	} {
+		dir := t.TempDir()
+		q := openTestDurableQueue(t, dir, opt)
+		for i := 0; i < 10; i++ {
+			if err := q.Push(i); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(100 * time.Microsecond)
+		}
+		if err := q.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		q.Close()
+		q = openTestDurableQueue(t, dir)
+		if q.Size() != 10 {
+			t.Errorf("policy %d: expected 10 items, got %d", q.opts.SyncPolicy, q.Size())
+		}
+		q.Close()
+	}
+}
+
+func TestDurableQueue_GobCodec(t *testing.T) {
+	type Job struct {
+		ID   int
+		Name string
+	}
+
+	dir := t.TempDir()
+	q, err := OpenDurableQueue[Job](dir, GobCodec[Job]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []Job{{1, "build"}, {2, "test"}, {3, "deploy"}}
+	for _, j := range jobs {
+		if err := q.Push(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.Close()
+
+	q, err = OpenDurableQueue[Job](dir, GobCodec[Job]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	for _, want := range jobs {
+		if _, got, ok := q.Pop(); !ok || got != want {
+			t.Errorf("expected %+v, got %+v (ok=%v)", want, got, ok)
+		}
+	}
+}
+
+// Example of using DurableQueue
+func ExampleDurableQueue() {
+	dir, _ := os.MkdirTemp("", "jobs")
+	defer os.RemoveAll(dir)
+
+	// Open (or create) a queue stored in dir, encoding items as JSON
+	q, err := OpenDurableQueue[string](dir, JSONCodec[string]{})
+	if err != nil {
+		return
+	}
+	defer q.Close()
+
+	// Pushes are written to the log before they are queued
+	_ = q.Push("send-email")
+
+	// Popped items stay in flight until they are acknowledged
+	id, job, ok := q.Pop() // job = "send-email", ok = true
+	_ = q.Ack(id)
+
+	// Prevent unused variable warnings in example
+	_, _ = job, ok
+}
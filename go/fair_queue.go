@@ -0,0 +1,155 @@
+package typed
+
+type FairQueueOptions struct {
+	QueueOptions                        // Options for every per-key sub-queue
+	MaxPerKey       int                 // Items a single key may hold, 0 for no limit
+	NewShrinkPolicy func() ShrinkPolicy // Creates the ShrinkPolicy of each sub-queue
+}
+
+type FairQueueOption func(*FairQueueOptions)
+
+func defaultFairQueueOptions() FairQueueOptions {
+	return FairQueueOptions{
+		QueueOptions: defaultQueueOptions(),
+	}
+}
+
+// WithFairQueueOptions configures the sub-queue of every key.
+func WithFairQueueOptions(queueOpts ...QueueOption) FairQueueOption {
+	return func(fo *FairQueueOptions) {
+		for _, opt := range queueOpts {
+			opt(&fo.QueueOptions)
+		}
+	}
+}
+
+func WithMaxPerKey(n int) FairQueueOption {
+	if n < 0 {
+		panic("Max items per key must not be negative")
+	}
+	return func(fo *FairQueueOptions) {
+		fo.MaxPerKey = n
+	}
+}
+
+// WithKeyShrinkPolicy gives every sub-queue its own policy from
+// newPolicy. A policy set through WithFairQueueOptions would be shared by
+// all keys, so NewFairQueue rejects it.
+func WithKeyShrinkPolicy(newPolicy func() ShrinkPolicy) FairQueueOption {
+	return func(fo *FairQueueOptions) {
+		fo.NewShrinkPolicy = newPolicy
+	}
+}
+
+// FairQueue keeps a FIFO sub-queue per key and serves the keys
+// round-robin, so one busy key cannot starve the others. A key is
+// forgotten as soon as its sub-queue is empty.
+type FairQueue[K comparable, T any] struct {
+	queues map[K]*Queue[T]
+	order  *Queue[K] // keys with pending items, in serving order
+	size   int
+	opts   FairQueueOptions
+}
+
+func NewFairQueue[K comparable, T any](opts ...FairQueueOption) *FairQueue[K, T] {
+	o := defaultFairQueueOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ShrinkPolicy != nil {
+		panic("FairQueue sub-queues cannot share a ShrinkPolicy, use WithKeyShrinkPolicy")
+	}
+	return &FairQueue[K, T]{
+		queues: make(map[K]*Queue[T]),
+		order:  NewQueue[K](),
+		opts:   o,
+	}
+}
+
+// Push adds val to the sub-queue of key. It returns false if key already
+// holds MaxPerKey items.
+func (f *FairQueue[K, T]) Push(key K, val T) bool {
+	q, ok := f.queues[key]
+	if !ok {
+		q = NewQueue[T](func(qo *QueueOptions) {
+			*qo = f.opts.QueueOptions
+			if f.opts.NewShrinkPolicy != nil {
+				qo.ShrinkPolicy = f.opts.NewShrinkPolicy()
+			}
+		})
+		f.queues[key] = q
+		f.order.Push(key)
+	} else if f.opts.MaxPerKey > 0 && q.Size() >= f.opts.MaxPerKey {
+		return false
+	}
+	q.Push(val)
+	f.size++
+	return true
+}
+
+// Pop removes the oldest item of the next key in turn.
+func (f *FairQueue[K, T]) Pop() (K, T, bool) {
+	var zero T
+	key, ok := f.order.Pop()
+	if !ok {
+		return key, zero, false
+	}
+	q := f.queues[key]
+	val, _ := q.Pop()
+	f.size--
+	if q.IsEmpty() {
+		delete(f.queues, key)
+	} else {
+		f.order.Push(key)
+	}
+	return key, val, true
+}
+
+// Peek returns the item Pop would return next.
+func (f *FairQueue[K, T]) Peek() (K, T, bool) {
+	var zero T
+	key, ok := f.order.Peek()
+	if !ok {
+		return key, zero, false
+	}
+	val, _ := f.queues[key].Peek()
+	return key, val, true
+}
+
+// Len returns the number of items held for key.
+func (f *FairQueue[K, T]) Len(key K) int {
+	if q, ok := f.queues[key]; ok {
+		return q.Size()
+	}
+	return 0
+}
+
+// Keys returns the number of keys with pending items.
+func (f *FairQueue[K, T]) Keys() int {
+	return len(f.queues)
+}
+
+// Remove drops every item held for key and returns how many there were.
+func (f *FairQueue[K, T]) Remove(key K) int {
+	q, ok := f.queues[key]
+	if !ok {
+		return 0
+	}
+	n := q.Size()
+	delete(f.queues, key)
+	for i := f.order.Size(); i > 0; i-- {
+		if k, _ := f.order.Pop(); k != key {
+			f.order.Push(k)
+		}
+	}
+	f.size -= n
+	return n
+}
+
+func (f *FairQueue[K, T]) Size() int {
+	return f.size
+}
+
+func (f *FairQueue[K, T]) IsEmpty() bool {
+	return f.size == 0
+}
@@ -0,0 +1,224 @@
+package typed
+
+import "testing"
+
+func TestFairQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+	type item struct {
+		key string
+		val int
+	}
+
+	tests := []struct {
+		name  string
+		opts  []FairQueueOption
+		steps []step
+	}{
+		{
+			name: "empty",
+			steps: []step{
+				{"isEmpty", nil, true},
+				{"pop", nil, nil},
+				{"peek", nil, nil},
+				{"keys", nil, 0},
+			},
+		},
+		{
+			name: "round-robin across keys",
+			steps: []step{
+				{"push", item{"a", 1}, true},
+				{"push", item{"a", 2}, true},
+				{"push", item{"a", 3}, true},
+				{"push", item{"b", 10}, true},
+				{"push", item{"c", 20}, true},
+				{"push", item{"b", 11}, true},
+				{"size", nil, 6},
+				{"keys", nil, 3},
+				{"len", "a", 3},
+				{"peek", nil, item{"a", 1}},
+				{"pop", nil, item{"a", 1}},
+				{"pop", nil, item{"b", 10}},
+				{"pop", nil, item{"c", 20}},
+				{"keys", nil, 2},
+				{"len", "c", 0},
+				{"pop", nil, item{"a", 2}},
+				{"pop", nil, item{"b", 11}},
+				{"pop", nil, item{"a", 3}},
+				{"pop", nil, nil},
+				{"keys", nil, 0},
+			},
+		},
+		{
+			name: "idle key rejoins at the back",
+			steps: []step{
+				{"push", item{"a", 1}, true},
+				{"push", item{"b", 2}, true},
+				{"pop", nil, item{"a", 1}},
+				{"push", item{"a", 3}, true},
+				{"pop", nil, item{"b", 2}},
+				{"pop", nil, item{"a", 3}},
+			},
+		},
+		{
+			name: "per-key limit",
+			opts: []FairQueueOption{WithMaxPerKey(2)},
+			steps: []step{
+				{"push", item{"a", 1}, true},
+				{"push", item{"a", 2}, true},
+				{"push", item{"a", 3}, false},
+				{"push", item{"b", 4}, true},
+				{"len", "a", 2},
+				{"pop", nil, item{"a", 1}},
+				{"push", item{"a", 5}, true},
+				{"size", nil, 3},
+			},
+		},
+		{
+			name: "remove key",
+			steps: []step{
+				{"push", item{"a", 1}, true},
+				{"push", item{"b", 2}, true},
+				{"push", item{"a", 3}, true},
+				{"push", item{"c", 4}, true},
+				{"remove", "a", 2},
+				{"remove", "a", 0},
+				{"size", nil, 2},
+				{"pop", nil, item{"b", 2}},
+				{"pop", nil, item{"c", 4}},
+				{"isEmpty", nil, true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewFairQueue[string, int](tt.opts...)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					it := step.value.(item)
+					if got := q.Push(it.key, it.val); got != step.expected.(bool) {
+						t.Errorf("step %d: push expected %v, got %v", i, step.expected, got)
+					}
+				case "pop", "peek":
+					var key string
+					var val int
+					var ok bool
+					if step.op == "pop" {
+						key, val, ok = q.Pop()
+					} else {
+						key, val, ok = q.Peek()
+					}
+					if step.expected == nil {
+						if ok {
+							t.Errorf("step %d: %s expected to fail, got %v=%v", i, step.op, key, val)
+						}
+					} else if got := (item{key, val}); !ok || got != step.expected.(item) {
+						t.Errorf("step %d: %s expected %v, got %v (ok=%v)", i, step.op, step.expected, got, ok)
+					}
+				case "len":
+					if got := q.Len(step.value.(string)); got != step.expected.(int) {
+						t.Errorf("step %d: len expected %v, got %v", i, step.expected, got)
+					}
+				case "remove":
+					if got := q.Remove(step.value.(string)); got != step.expected.(int) {
+						t.Errorf("step %d: remove expected %v, got %v", i, step.expected, got)
+					}
+				case "keys":
+					if got := q.Keys(); got != step.expected.(int) {
+						t.Errorf("step %d: keys expected %v, got %v", i, step.expected, got)
+					}
+				case "size":
+					if got := q.Size(); got != step.expected.(int) {
+						t.Errorf("step %d: size expected %v, got %v", i, step.expected, got)
+					}
+				case "isEmpty":
+					if got := q.IsEmpty(); got != step.expected.(bool) {
+						t.Errorf("step %d: isEmpty expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestFairQueue_NoStarvation(t *testing.T) {
+	q := NewFairQueue[int, int](WithFairQueueOptions(WithQueueLimitOptions(WithShrinkThresholdCap(8))))
+	for i := 0; i < 1000; i++ {
+		q.Push(0, i) // noisy tenant
+	}
+	for k := 1; k <= 3; k++ {
+		q.Push(k, k)
+	}
+	served := map[int]bool{}
+	for i := 0; i < 4; i++ {
+		key, _, _ := q.Pop()
+		served[key] = true
+	}
+	if len(served) != 4 {
+		t.Errorf("expected every tenant to be served within one round, got %v", served)
+	}
+	for i := 1; !q.IsEmpty(); i++ {
+		if _, val, _ := q.Pop(); val != i {
+			t.Fatalf("expected %d, got %d", i, val)
+		}
+	}
+	if q.Keys() != 0 {
+		t.Errorf("expected idle keys to be removed, got %d", q.Keys())
+	}
+}
+
+func TestFairQueue_ShrinkPolicyPerKey(t *testing.T) {
+	var policies []*countingShrinkPolicy
+	q := NewFairQueue[string, int](WithKeyShrinkPolicy(func() ShrinkPolicy {
+		p := &countingShrinkPolicy{ShrinkPolicy: NewRatioShrinkPolicy(0, 0.25, 0.5)}
+		policies = append(policies, p)
+		return p
+	}))
+	for i := 0; i < 20; i++ {
+		q.Push("a", i)
+		q.Push("b", i)
+	}
+	if len(policies) != 2 {
+		t.Fatalf("expected a policy per key, got %d", len(policies))
+	}
+	for i, p := range policies {
+		if p.resizes == 0 {
+			t.Errorf("policy %d: expected its own sub-queue to report resizes", i)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a shared ShrinkPolicy")
+		}
+	}()
+	NewFairQueue[string, int](WithFairQueueOptions(WithQueueLimitOptions(
+		WithShrinkPolicy(NewRatioShrinkPolicy(0, 0.25, 0.5)))))
+}
+
+// Example of using FairQueue
+func ExampleFairQueue() {
+	// One sub-queue per tenant, at most 1000 pending jobs each
+	q := NewFairQueue[string, string](WithMaxPerKey(1000))
+
+	q.Push("tenant-a", "job-1")
+	q.Push("tenant-a", "job-2")
+	q.Push("tenant-b", "job-3")
+
+	// Tenants are served in turn: job-1, job-3, job-2
+	tenant, job, ok := q.Pop()
+
+	// Pending jobs of one tenant
+	pending := q.Len("tenant-a")
+
+	// Prevent unused variable warnings in example
+	_, _, _, _ = tenant, job, ok, pending
+}
@@ -10,17 +10,60 @@ import (
 // Should return true if a has higher priority than b.
 type Comparator[T any] internal.Comparator[T]
 
+type HeapOptions struct {
+	GrowthOptions
+	ResizeHooks
+}
+
+type HeapOption func(*HeapOptions)
+
+func defaultHeapOptions() HeapOptions {
+	growth := DefaultGrowthOptions()
+	growth.InitialCapacity = 0 // heaps allocate on first Push
+	return HeapOptions{
+		GrowthOptions: growth,
+	}
+}
+
+func WithHeapGrowthOptions(growthOpts ...GrowthOption) HeapOption {
+	return func(ho *HeapOptions) {
+		for _, opt := range growthOpts {
+			opt(&ho.GrowthOptions)
+		}
+	}
+}
+
+func WithHeapResizeHooks(hooks ...ResizeHook) HeapOption {
+	return func(ho *HeapOptions) {
+		for _, hook := range hooks {
+			hook(&ho.ResizeHooks)
+		}
+	}
+}
+
 type Heap[T any] struct {
 	inner *internal.Heap[T]
+	opts  HeapOptions
+	stats Stats
 }
 
 // NewHeap creates a new heap using the provided comparator.
-func NewHeap[T any](cmp Comparator[T]) *Heap[T] {
-	return &Heap[T]{inner: internal.NewHeap(cmp)}
+func NewHeap[T any](cmp Comparator[T], opts ...HeapOption) *Heap[T] {
+	o := defaultHeapOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	h := &Heap[T]{inner: internal.NewHeap(cmp), opts: o}
+	h.inner.Reserve(o.InitialCapacity)
+	return h
 }
 
 func (h *Heap[T]) Push(x T) {
+	if n := h.inner.Len(); n == h.inner.Cap() {
+		h.Reserve(h.opts.nextCap(n, n+1))
+	}
 	heap.Push(h.inner, x)
+	h.stats.recordPush(h.inner.Len())
 }
 
 func (h *Heap[T]) Pop() (T, bool) {
@@ -28,6 +71,7 @@ func (h *Heap[T]) Pop() (T, bool) {
 	if h.inner.Len() == 0 {
 		return zero, false
 	}
+	h.stats.recordPop()
 	return heap.Pop(h.inner).(T), true
 }
 
@@ -39,6 +83,24 @@ func (h *Heap[T]) Size() int {
 	return h.inner.Len()
 }
 
+func (h *Heap[T]) Cap() int {
+	return h.inner.Cap()
+}
+
+// Reserve makes room for at least n items, so the heap does not grow
+// again until it holds more than n.
+func (h *Heap[T]) Reserve(n int) {
+	if c := h.inner.Cap(); n > c {
+		h.inner.Reserve(n)
+		recordResize[T](&h.stats, h.opts.ResizeHooks, c, n, h.inner.Len())
+	}
+}
+
+// Stats returns usage counters for the heap.
+func (h *Heap[T]) Stats() Stats {
+	return h.stats
+}
+
 func (h *Heap[T]) ItemsCopy() []T {
 	return h.inner.ItemsCopy()
 }
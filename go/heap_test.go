@@ -225,6 +225,37 @@ func TestHeap_PropertyMaintained(t *testing.T) {
 	}
 }
 
+func TestHeap_Growth(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []GrowthOption
+		reserve  int
+		push     int
+		expected int
+	}{
+		{"initial capacity", []GrowthOption{WithInitialCapacity(16)}, 0, 16, 16},
+		{"growth factor", []GrowthOption{WithInitialCapacity(16), WithGrowthFactor(1.5)}, 0, 17, 24},
+		{"max growth step", []GrowthOption{WithInitialCapacity(16), WithMaxGrowthStep(4)}, 0, 21, 24},
+		{"reserve", nil, 100, 100, 100},
+		{"reserve below capacity", []GrowthOption{WithInitialCapacity(16)}, 8, 10, 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHeap[int](func(a, b int) bool { return a < b }, WithHeapGrowthOptions(tt.opts...))
+			if tt.reserve > 0 {
+				h.Reserve(tt.reserve)
+			}
+			for i := 0; i < tt.push; i++ {
+				h.Push(i)
+			}
+			if got := h.Cap(); got != tt.expected {
+				t.Errorf("expected capacity %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
 // Example of using Heap
 func ExampleHeap() {
 	// Create a min-heap for integers
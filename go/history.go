@@ -0,0 +1,96 @@
+package typed
+
+// History records actions for multi-level undo and redo. It keeps at most
+// MaxDepth undo entries, dropping the oldest when full, and a new action
+// discards everything that could be redone.
+type History[T any] struct {
+	undo     *Deque[T] // oldest action at the front
+	redo     *Stack[T]
+	maxDepth int
+}
+
+// NewHistory creates a History that keeps up to maxDepth undo entries, or
+// any number if maxDepth is 0.
+func NewHistory[T any](maxDepth int) *History[T] {
+	if maxDepth < 0 {
+		panic("History max depth must not be negative")
+	}
+	return &History[T]{
+		undo:     NewDeque[T](),
+		redo:     NewStack[T](),
+		maxDepth: maxDepth,
+	}
+}
+
+// Do records val as the latest action and clears the redo entries.
+func (h *History[T]) Do(val T) {
+	h.undo.PushBack(val)
+	h.redo.Reset()
+	h.trim()
+}
+
+// Undo returns the latest action and moves it to the redo side.
+func (h *History[T]) Undo() (T, bool) {
+	val, ok := h.undo.PopBack()
+	if ok {
+		h.redo.Push(val)
+	}
+	return val, ok
+}
+
+// Redo returns the most recently undone action and moves it back to the
+// undo side.
+func (h *History[T]) Redo() (T, bool) {
+	val, ok := h.redo.Pop()
+	if ok {
+		h.undo.PushBack(val)
+	}
+	return val, ok
+}
+
+func (h *History[T]) CanUndo() bool {
+	return !h.undo.IsEmpty()
+}
+
+func (h *History[T]) CanRedo() bool {
+	return !h.redo.IsEmpty()
+}
+
+// UndoLen returns the number of actions that can be undone.
+func (h *History[T]) UndoLen() int {
+	return h.undo.Size()
+}
+
+// RedoLen returns the number of actions that can be redone.
+func (h *History[T]) RedoLen() int {
+	return h.redo.Len()
+}
+
+func (h *History[T]) MaxDepth() int {
+	return h.maxDepth
+}
+
+// SetMaxDepth changes the depth limit, dropping the oldest undo entries
+// if there are now too many.
+func (h *History[T]) SetMaxDepth(maxDepth int) {
+	if maxDepth < 0 {
+		panic("History max depth must not be negative")
+	}
+	h.maxDepth = maxDepth
+	h.trim()
+}
+
+// Clear forgets all undo and redo entries.
+func (h *History[T]) Clear() {
+	h.undo.Reset()
+	h.redo.Reset()
+}
+
+func (h *History[T]) trim() {
+	if h.maxDepth == 0 {
+		return
+	}
+	for h.undo.Size() > h.maxDepth {
+		h.undo.PopFront()
+	}
+}
@@ -0,0 +1,144 @@
+package typed
+
+import "testing"
+
+func TestHistory(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		steps    []step
+	}{
+		{
+			name: "undo and redo",
+			steps: []step{
+				{"canUndo", nil, false},
+				{"undo", nil, nil},
+				{"do", "a", nil},
+				{"do", "b", nil},
+				{"do", "c", nil},
+				{"undo", nil, "c"},
+				{"undo", nil, "b"},
+				{"canRedo", nil, true},
+				{"redo", nil, "b"},
+				{"undoLen", nil, 2},
+				{"redoLen", nil, 1},
+				{"redo", nil, "c"},
+				{"redo", nil, nil},
+			},
+		},
+		{
+			name: "new action clears redo",
+			steps: []step{
+				{"do", "a", nil},
+				{"do", "b", nil},
+				{"undo", nil, "b"},
+				{"do", "x", nil},
+				{"canRedo", nil, false},
+				{"undo", nil, "x"},
+				{"undo", nil, "a"},
+				{"canUndo", nil, false},
+			},
+		},
+		{
+			name:     "oldest entries fall off",
+			maxDepth: 2,
+			steps: []step{
+				{"do", "a", nil},
+				{"do", "b", nil},
+				{"do", "c", nil},
+				{"undoLen", nil, 2},
+				{"undo", nil, "c"},
+				{"undo", nil, "b"},
+				{"undo", nil, nil},
+				{"redo", nil, "b"},
+				{"redo", nil, "c"},
+			},
+		},
+		{
+			name: "lower max depth",
+			steps: []step{
+				{"do", "a", nil},
+				{"do", "b", nil},
+				{"do", "c", nil},
+				{"setMaxDepth", 1, nil},
+				{"undoLen", nil, 1},
+				{"undo", nil, "c"},
+				{"undo", nil, nil},
+				{"clear", nil, nil},
+				{"canRedo", nil, false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHistory[string](tt.maxDepth)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "do":
+					h.Do(step.value.(string))
+				case "undo", "redo":
+					var val string
+					var ok bool
+					if step.op == "undo" {
+						val, ok = h.Undo()
+					} else {
+						val, ok = h.Redo()
+					}
+					if step.expected == nil {
+						if ok {
+							t.Errorf("step %d: %s expected to fail, got %v", i, step.op, val)
+						}
+					} else if !ok || val != step.expected.(string) {
+						t.Errorf("step %d: %s expected %v, got %v (ok=%v)", i, step.op, step.expected, val, ok)
+					}
+				case "canUndo":
+					if got := h.CanUndo(); got != step.expected.(bool) {
+						t.Errorf("step %d: canUndo expected %v, got %v", i, step.expected, got)
+					}
+				case "canRedo":
+					if got := h.CanRedo(); got != step.expected.(bool) {
+						t.Errorf("step %d: canRedo expected %v, got %v", i, step.expected, got)
+					}
+				case "undoLen":
+					if got := h.UndoLen(); got != step.expected.(int) {
+						t.Errorf("step %d: undoLen expected %v, got %v", i, step.expected, got)
+					}
+				case "redoLen":
+					if got := h.RedoLen(); got != step.expected.(int) {
+						t.Errorf("step %d: redoLen expected %v, got %v", i, step.expected, got)
+					}
+				case "setMaxDepth":
+					h.SetMaxDepth(step.value.(int))
+				case "clear":
+					h.Clear()
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+// Example of using History
+func ExampleHistory() {
+	// Keep the last 100 edits
+	h := NewHistory[string](100)
+
+	h.Do("insert 'hello'")
+	h.Do("delete line 3")
+
+	// Step back and forward
+	edit, ok := h.Undo() // "delete line 3"
+	edit, ok = h.Redo()  // "delete line 3" again
+
+	// Prevent unused variable warnings in example
+	_, _ = edit, ok
+}
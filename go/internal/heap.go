@@ -46,6 +46,19 @@ func (h *Heap[T]) Peek() (T, bool) {
 	return h.items[0], true
 }
 
+func (h *Heap[T]) Cap() int {
+	return cap(h.items)
+}
+
+// Reserve grows the backing slice to hold at least n items.
+func (h *Heap[T]) Reserve(n int) {
+	if n > cap(h.items) {
+		items := make([]T, len(h.items), n)
+		copy(items, h.items)
+		h.items = items
+	}
+}
+
 func (h *Heap[T]) ItemsCopy() []T {
 	cp := make([]T, len(h.items))
 	copy(cp, h.items)
@@ -0,0 +1,298 @@
+package typed
+
+import "time"
+
+type LeaseQueueOptions struct {
+	VisibilityTimeout time.Duration // How long a received item stays leased
+	MaxAttempts       int           // Deliveries before an item is dead-lettered, 0 for no limit
+	BackoffBase       time.Duration // Delay before the first redelivery, doubled on each retry
+	BackoffMax        time.Duration // Upper bound of the redelivery delay
+	Clock             func() time.Time
+}
+
+type LeaseQueueOption func(*LeaseQueueOptions)
+
+func defaultLeaseQueueOptions() LeaseQueueOptions {
+	return LeaseQueueOptions{
+		VisibilityTimeout: 30 * time.Second,
+		MaxAttempts:       5,
+		BackoffBase:       time.Second,
+		BackoffMax:        time.Minute,
+		Clock:             time.Now,
+	}
+}
+
+func WithVisibilityTimeout(d time.Duration) LeaseQueueOption {
+	if d <= 0 {
+		panic("Visibility timeout must be greater than 0")
+	}
+	return func(lo *LeaseQueueOptions) {
+		lo.VisibilityTimeout = d
+	}
+}
+
+func WithMaxAttempts(n int) LeaseQueueOption {
+	if n < 0 {
+		panic("Max attempts must not be negative")
+	}
+	return func(lo *LeaseQueueOptions) {
+		lo.MaxAttempts = n
+	}
+}
+
+// WithBackoff sets the redelivery delay to base * 2^(retry-1), capped at
+// maxDelay. A zero base redelivers immediately.
+func WithBackoff(base, maxDelay time.Duration) LeaseQueueOption {
+	if base < 0 || maxDelay < base {
+		panic("Backoff must satisfy 0 <= base <= maxDelay")
+	}
+	return func(lo *LeaseQueueOptions) {
+		lo.BackoffBase = base
+		lo.BackoffMax = maxDelay
+	}
+}
+
+// WithLeaseClock replaces time.Now as the source of the current time.
+func WithLeaseClock(now func() time.Time) LeaseQueueOption {
+	if now == nil {
+		panic("Lease clock must not be nil")
+	}
+	return func(lo *LeaseQueueOptions) {
+		lo.Clock = now
+	}
+}
+
+// Delivery is an item handed out by LeaseQueue.Receive, together with its
+// delivery metadata.
+type Delivery[T any] struct {
+	ID         uint64
+	Value      T
+	Attempts   int       // Deliveries so far, including this one
+	EnqueuedAt time.Time // When the item was pushed
+	Deadline   time.Time // When the lease expires
+}
+
+type leaseEntry[T any] struct {
+	Delivery[T]
+	leased bool
+	gen    uint64 // bumped on every state change to invalidate old timers
+}
+
+type leaseTimer[T any] struct {
+	at  time.Time
+	e   *leaseEntry[T]
+	gen uint64
+}
+
+// LeaseQueue is an in-process queue with SQS-like delivery. Receive leases
+// an item for the visibility timeout; Ack deletes it, while Nack or an
+// expired lease makes it visible again after an exponential backoff. An
+// item delivered MaxAttempts times without an Ack moves to the dead-letter
+// queue.
+//
+// A LeaseQueue is not safe for concurrent use.
+type LeaseQueue[T any] struct {
+	opts    LeaseQueueOptions
+	ready   *Queue[*leaseEntry[T]]
+	timers  *Heap[leaseTimer[T]] // lease expiries and backoff delays
+	stale   int                  // timers in the heap that no longer apply
+	entries map[uint64]*leaseEntry[T]
+	dead    *Queue[Delivery[T]]
+	leases  int
+	nextID  uint64
+}
+
+func NewLeaseQueue[T any](opts ...LeaseQueueOption) *LeaseQueue[T] {
+	o := defaultLeaseQueueOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &LeaseQueue[T]{
+		opts:    o,
+		ready:   NewQueue[*leaseEntry[T]](),
+		timers:  newLeaseTimers[T](),
+		entries: make(map[uint64]*leaseEntry[T]),
+		dead:    NewQueue[Delivery[T]](),
+	}
+}
+
+func newLeaseTimers[T any]() *Heap[leaseTimer[T]] {
+	return NewHeap[leaseTimer[T]](func(a, b leaseTimer[T]) bool { return a.at.Before(b.at) })
+}
+
+// Push adds val and returns its id.
+func (q *LeaseQueue[T]) Push(val T) uint64 {
+	q.nextID++
+	e := &leaseEntry[T]{Delivery: Delivery[T]{
+		ID:         q.nextID,
+		Value:      val,
+		EnqueuedAt: q.opts.Clock(),
+	}}
+	q.entries[e.ID] = e
+	q.ready.Push(e)
+	return e.ID
+}
+
+// Receive leases the oldest visible item. ok is false if no item is
+// visible right now.
+func (q *LeaseQueue[T]) Receive() (d Delivery[T], ok bool) {
+	now := q.opts.Clock()
+	q.tick(now)
+	e, ok := q.ready.Pop()
+	if !ok {
+		return d, false
+	}
+	e.leased = true
+	q.leases++
+	e.Attempts++
+	e.Deadline = now.Add(q.opts.VisibilityTimeout)
+	q.schedule(e, e.Deadline)
+	return e.Delivery, true
+}
+
+// Ack deletes a leased item. It returns ErrNotInFlight if id is unknown
+// or its lease has expired.
+func (q *LeaseQueue[T]) Ack(id uint64) error {
+	e, err := q.leased(id)
+	if err != nil {
+		return err
+	}
+	e.gen++
+	q.leases--
+	delete(q.entries, id)
+	// The stale timer may outlive the entry, so do not let it pin the value
+	var zero T
+	e.Value = zero
+	q.dropTimer()
+	return nil
+}
+
+// Nack gives up the lease on id, so the item is redelivered after the
+// backoff or dead-lettered.
+func (q *LeaseQueue[T]) Nack(id uint64) error {
+	e, err := q.leased(id)
+	if err != nil {
+		return err
+	}
+	q.retry(e, q.opts.Clock())
+	q.dropTimer()
+	return nil
+}
+
+// Extend resets the lease on id to expire timeout from now.
+func (q *LeaseQueue[T]) Extend(id uint64, timeout time.Duration) error {
+	e, err := q.leased(id)
+	if err != nil {
+		return err
+	}
+	e.Deadline = q.opts.Clock().Add(timeout)
+	q.schedule(e, e.Deadline)
+	q.dropTimer()
+	return nil
+}
+
+// DeadLetter returns the queue of items that ran out of attempts,
+// including those whose last lease has just expired.
+func (q *LeaseQueue[T]) DeadLetter() *Queue[Delivery[T]] {
+	q.tick(q.opts.Clock())
+	return q.dead
+}
+
+// Size returns the number of items not yet acked or dead-lettered,
+// including leased and delayed ones.
+func (q *LeaseQueue[T]) Size() int {
+	q.tick(q.opts.Clock())
+	return len(q.entries)
+}
+
+func (q *LeaseQueue[T]) IsEmpty() bool {
+	return q.Size() == 0
+}
+
+// InFlight returns the number of leased items.
+func (q *LeaseQueue[T]) InFlight() int {
+	q.tick(q.opts.Clock())
+	return q.leases
+}
+
+func (q *LeaseQueue[T]) leased(id uint64) (*leaseEntry[T], error) {
+	q.tick(q.opts.Clock())
+	e, ok := q.entries[id]
+	if !ok || !e.leased {
+		return nil, ErrNotInFlight
+	}
+	return e, nil
+}
+
+// tick fires every timer due at now.
+func (q *LeaseQueue[T]) tick(now time.Time) {
+	for {
+		t, ok := q.timers.Peek()
+		if !ok || t.at.After(now) {
+			return
+		}
+		q.timers.Pop()
+		if t.gen != t.e.gen {
+			q.stale--
+			continue // acked, nacked or rescheduled since
+		}
+		if t.e.leased {
+			q.retry(t.e, t.at)
+		} else {
+			t.e.gen++
+			q.ready.Push(t.e)
+		}
+	}
+}
+
+// retry ends the current delivery of e, dead-lettering it or scheduling
+// its redelivery.
+func (q *LeaseQueue[T]) retry(e *leaseEntry[T], now time.Time) {
+	e.leased = false
+	q.leases--
+	e.gen++
+	if q.opts.MaxAttempts > 0 && e.Attempts >= q.opts.MaxAttempts {
+		delete(q.entries, e.ID)
+		q.dead.Push(e.Delivery)
+		// As in Ack, a stale timer may outlive the entry
+		var zero T
+		e.Value = zero
+		return
+	}
+	if delay := q.backoff(e.Attempts); delay > 0 {
+		q.schedule(e, now.Add(delay))
+		return
+	}
+	q.ready.Push(e)
+}
+
+func (q *LeaseQueue[T]) schedule(e *leaseEntry[T], at time.Time) {
+	e.gen++
+	q.timers.Push(leaseTimer[T]{at: at, e: e, gen: e.gen})
+}
+
+// dropTimer counts a timer made stale outside tick, and rebuilds the heap
+// once stale timers outnumber live ones, so they do not pile up until
+// their deadlines.
+func (q *LeaseQueue[T]) dropTimer() {
+	q.stale++
+	if q.stale <= q.timers.Size()/2 {
+		return
+	}
+	timers := newLeaseTimers[T]()
+	for _, t := range q.timers.ItemsCopy() {
+		if t.gen == t.e.gen {
+			timers.Push(t)
+		}
+	}
+	q.timers = timers
+	q.stale = 0
+}
+
+func (q *LeaseQueue[T]) backoff(attempts int) time.Duration {
+	d := q.opts.BackoffBase
+	for i := 1; i < attempts && d < q.opts.BackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, q.opts.BackoffMax)
+}
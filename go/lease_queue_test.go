@@ -0,0 +1,298 @@
+package typed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaseQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+	// receive expectations: value and attempt number
+	type delivery struct {
+		val      string
+		attempts int
+	}
+
+	tests := []struct {
+		name  string
+		opts  []LeaseQueueOption
+		steps []step
+	}{
+		{
+			name: "ack removes the item",
+			steps: []step{
+				{"push", "a", nil},
+				{"push", "b", nil},
+				{"receive", nil, delivery{"a", 1}},
+				{"inFlight", nil, 1},
+				{"ack", "a", nil},
+				{"ack", "a", ErrNotInFlight},
+				{"size", nil, 1},
+				{"receive", nil, delivery{"b", 1}},
+				{"receive", nil, nil},
+			},
+		},
+		{
+			name: "expired lease redelivers after backoff",
+			steps: []step{
+				{"push", "a", nil},
+				{"receive", nil, delivery{"a", 1}},
+				{"advance", 29 * time.Second, nil},
+				{"receive", nil, nil},
+				{"advance", time.Second, nil}, // lease expires, 1s backoff starts
+				{"inFlight", nil, 0},
+				{"ack", "a", ErrNotInFlight},
+				{"receive", nil, nil},
+				{"advance", time.Second, nil},
+				{"receive", nil, delivery{"a", 2}},
+			},
+		},
+		{
+			name: "nack backs off exponentially",
+			opts: []LeaseQueueOption{WithBackoff(time.Second, 3*time.Second), WithMaxAttempts(0)},
+			steps: []step{
+				{"push", "a", nil},
+				{"receive", nil, delivery{"a", 1}},
+				{"nack", "a", nil},
+				{"advance", time.Second, nil},
+				{"receive", nil, delivery{"a", 2}},
+				{"nack", "a", nil},
+				{"advance", time.Second, nil},
+				{"receive", nil, nil},
+				{"advance", time.Second, nil},
+				{"receive", nil, delivery{"a", 3}},
+				{"nack", "a", nil},
+				{"advance", 2 * time.Second, nil},
+				{"receive", nil, nil}, // capped at 3s
+				{"advance", time.Second, nil},
+				{"receive", nil, delivery{"a", 4}},
+			},
+		},
+		{
+			name: "dead-letter after max attempts",
+			opts: []LeaseQueueOption{WithMaxAttempts(2), WithBackoff(0, 0)},
+			steps: []step{
+				{"push", "a", nil},
+				{"push", "b", nil},
+				{"receive", nil, delivery{"a", 1}},
+				{"nack", "a", nil},
+				{"receive", nil, delivery{"b", 1}},
+				{"receive", nil, delivery{"a", 2}},
+				{"advance", 30 * time.Second, nil}, // both leases expire
+				{"dead", nil, []string{"a"}},
+				{"size", nil, 1},
+				{"receive", nil, delivery{"b", 2}},
+				{"nack", "b", nil},
+				{"dead", nil, []string{"a", "b"}},
+				{"size", nil, 0},
+			},
+		},
+		{
+			name: "extend keeps the lease",
+			opts: []LeaseQueueOption{WithVisibilityTimeout(10 * time.Second)},
+			steps: []step{
+				{"push", "a", nil},
+				{"receive", nil, delivery{"a", 1}},
+				{"advance", 8 * time.Second, nil},
+				{"extend", "a", nil},
+				{"advance", 8 * time.Second, nil},
+				{"inFlight", nil, 1},
+				{"ack", "a", nil},
+				{"advance", time.Minute, nil},
+				{"receive", nil, nil},
+				{"size", nil, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			q := NewLeaseQueue[string](append(tt.opts, WithLeaseClock(func() time.Time { return now }))...)
+			ids := map[string]uint64{}
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					ids[step.value.(string)] = q.Push(step.value.(string))
+				case "receive":
+					d, ok := q.Receive()
+					if step.expected == nil {
+						if ok {
+							t.Errorf("step %d: receive expected nothing, got %+v", i, d)
+						}
+						continue
+					}
+					want := step.expected.(delivery)
+					if !ok || d.Value != want.val || d.Attempts != want.attempts || d.ID != ids[want.val] {
+						t.Errorf("step %d: receive expected %+v, got %+v (ok=%v)", i, want, d, ok)
+					}
+					if !d.Deadline.After(now) || !d.EnqueuedAt.Equal(time.Unix(0, 0)) {
+						t.Errorf("step %d: unexpected times in %+v", i, d)
+					}
+				case "ack", "nack", "extend":
+					var err error
+					switch step.op {
+					case "ack":
+						err = q.Ack(ids[step.value.(string)])
+					case "nack":
+						err = q.Nack(ids[step.value.(string)])
+					case "extend":
+						err = q.Extend(ids[step.value.(string)], 10*time.Second)
+					}
+					if expected, _ := step.expected.(error); err != expected {
+						t.Errorf("step %d: %s expected %v, got %v", i, step.op, expected, err)
+					}
+				case "advance":
+					now = now.Add(step.value.(time.Duration))
+				case "inFlight":
+					if got := q.InFlight(); got != step.expected.(int) {
+						t.Errorf("step %d: inFlight expected %v, got %v", i, step.expected, got)
+					}
+				case "size":
+					if got := q.Size(); got != step.expected.(int) {
+						t.Errorf("step %d: size expected %v, got %v", i, step.expected, got)
+					}
+				case "dead":
+					var got []string
+					q.DeadLetter().Snapshot().Range(func(_ int, d Delivery[string]) bool {
+						got = append(got, d.Value)
+						return true
+					})
+					want := step.expected.([]string)
+					if len(got) != len(want) {
+						t.Fatalf("step %d: dead expected %v, got %v", i, want, got)
+					}
+					for j := range want {
+						if got[j] != want[j] {
+							t.Errorf("step %d: dead expected %v, got %v", i, want, got)
+						}
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestLeaseQueue_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  func() LeaseQueueOption
+	}{
+		{"zero visibility timeout", func() LeaseQueueOption { return WithVisibilityTimeout(0) }},
+		{"negative attempts", func() LeaseQueueOption { return WithMaxAttempts(-1) }},
+		{"backoff max below base", func() LeaseQueueOption { return WithBackoff(time.Second, time.Millisecond) }},
+		{"nil clock", func() LeaseQueueOption { return WithLeaseClock(nil) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic")
+				}
+			}()
+			tt.opt()
+		})
+	}
+}
+
+func TestLeaseQueue_StaleTimers(t *testing.T) {
+	now := time.Unix(0, 0)
+	q := NewLeaseQueue[*[64]byte](WithLeaseClock(func() time.Time { return now }))
+
+	// Acked items must not stay reachable through their lease timers
+	var acked []*leaseEntry[*[64]byte]
+	for i := 0; i < 1000; i++ {
+		q.Push(new([64]byte))
+		d, _ := q.Receive()
+		acked = append(acked, q.entries[d.ID])
+		if err := q.Ack(d.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := q.timers.Size(); n > 1 {
+		t.Errorf("expected stale timers to be dropped, %d left", n)
+	}
+	for _, e := range acked {
+		if e.Value != nil {
+			t.Fatalf("expected acked value %d to be released", e.ID)
+		}
+	}
+
+	// Extending one lease over and over keeps the heap small
+	id := q.Push(new([64]byte))
+	q.Receive()
+	for i := 0; i < 1000; i++ {
+		if err := q.Extend(id, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := q.timers.Size(); n > 3 {
+		t.Errorf("expected extended timers to be dropped, %d left", n)
+	}
+
+	// The lease still expires on its latest deadline
+	now = now.Add(time.Minute - time.Nanosecond)
+	if q.InFlight() != 1 {
+		t.Fatalf("expected lease to hold until the extended deadline")
+	}
+	now = now.Add(time.Nanosecond)
+	if q.InFlight() != 0 {
+		t.Errorf("expected lease to expire at the extended deadline")
+	}
+
+	// Dead-lettered items are only reachable through the dead-letter queue
+	q = NewLeaseQueue[*[64]byte](WithLeaseClock(func() time.Time { return now }), WithMaxAttempts(1))
+	var dead []*leaseEntry[*[64]byte]
+	for i := 0; i < 1000; i++ {
+		q.Push(new([64]byte))
+		d, _ := q.Receive()
+		dead = append(dead, q.entries[d.ID])
+		if err := q.Nack(d.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := q.timers.Size(); n > 1 {
+		t.Errorf("expected stale timers to be dropped, %d left", n)
+	}
+	for _, e := range dead {
+		if e.Value != nil {
+			t.Fatalf("expected dead-lettered value %d to be released", e.ID)
+		}
+	}
+	if d, ok := q.DeadLetter().Pop(); !ok || d.Value == nil {
+		t.Errorf("expected dead letter to keep its value, got %v (ok=%v)", d.Value, ok)
+	}
+}
+
+// Example of using LeaseQueue
+func ExampleLeaseQueue() {
+	q := NewLeaseQueue[string](
+		WithVisibilityTimeout(time.Minute),
+		WithMaxAttempts(3),
+		WithBackoff(time.Second, time.Minute),
+	)
+	q.Push("resize image 42")
+
+	d, ok := q.Receive() // leased for a minute
+	if ok {
+		// Process d.Value; d.Attempts counts deliveries
+		if err := error(nil); err != nil {
+			_ = q.Nack(d.ID) // redelivered after the backoff
+		} else {
+			_ = q.Ack(d.ID)
+		}
+	}
+
+	// Items that failed 3 times
+	dead := q.DeadLetter()
+
+	// Prevent unused variable warnings in example
+	_ = dead
+}
@@ -0,0 +1,199 @@
+package typed
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+type mpmcCell[T any] struct {
+	seq atomic.Uint64
+	val T
+}
+
+// MPMCQueue is a bounded, lock-free FIFO queue that is safe for any number
+// of concurrent producers and consumers. Each slot carries a sequence number
+// that tells producers and consumers whether it is ready for them, so
+// operations on different slots never contend on a shared lock.
+//
+// TryEnqueue and TryDequeue may report full or empty while another
+// goroutine is still in the middle of writing or reading the slot they need.
+type MPMCQueue[T any] struct {
+	_     [cacheLineSize]byte
+	tail  atomic.Uint64 // next enqueue position
+	_     [cacheLineSize - 8]byte
+	head  atomic.Uint64 // next dequeue position
+	_     [cacheLineSize - 8]byte
+	cells []mpmcCell[T]
+	mask  uint64
+
+	// Enqueue and Dequeue park on these channels instead of spinning. The
+	// Try methods only signal them while someone is parked.
+	items, space              chan struct{}
+	itemWaiters, spaceWaiters atomic.Int32
+}
+
+// NewMPMCQueue creates a queue that holds up to capacity items.
+// The capacity must be a power of two and at least 2.
+func NewMPMCQueue[T any](capacity int) *MPMCQueue[T] {
+	if capacity < 2 || capacity&(capacity-1) != 0 {
+		panic("MPMCQueue capacity must be a power of two and at least 2")
+	}
+	q := &MPMCQueue[T]{
+		cells: make([]mpmcCell[T], capacity),
+		mask:  uint64(capacity - 1),
+		items: make(chan struct{}, capacity),
+		space: make(chan struct{}, capacity),
+	}
+	for i := range q.cells {
+		q.cells[i].seq.Store(uint64(i))
+	}
+	return q
+}
+
+// TryEnqueue adds val to the queue. It returns false if the queue is full.
+func (q *MPMCQueue[T]) TryEnqueue(val T) bool {
+	pos := q.tail.Load()
+	for {
+		cell := &q.cells[pos&q.mask]
+		seq := cell.seq.Load()
+		switch dif := int64(seq - pos); {
+		case dif == 0:
+			if q.tail.CompareAndSwap(pos, pos+1) {
+				cell.val = val
+				cell.seq.Store(pos + 1)
+				wake(&q.itemWaiters, q.items)
+				return true
+			}
+			pos = q.tail.Load()
+		case dif < 0:
+			return false
+		default:
+			pos = q.tail.Load()
+		}
+	}
+}
+
+// TryDequeue removes the oldest item. It returns false if the queue is empty.
+func (q *MPMCQueue[T]) TryDequeue() (T, bool) {
+	var zero T
+	pos := q.head.Load()
+	for {
+		cell := &q.cells[pos&q.mask]
+		seq := cell.seq.Load()
+		switch dif := int64(seq - (pos + 1)); {
+		case dif == 0:
+			if q.head.CompareAndSwap(pos, pos+1) {
+				val := cell.val
+				cell.val = zero
+				cell.seq.Store(pos + q.mask + 1)
+				wake(&q.spaceWaiters, q.space)
+				return val, true
+			}
+			pos = q.head.Load()
+		case dif < 0:
+			return zero, false
+		default:
+			pos = q.head.Load()
+		}
+	}
+}
+
+// Enqueue adds val to the queue, waiting for space until ctx is done.
+func (q *MPMCQueue[T]) Enqueue(ctx context.Context, val T) error {
+	for {
+		if q.TryEnqueue(val) {
+			break
+		}
+		err := park(ctx, &q.spaceWaiters, q.space, func() bool { return q.TryEnqueue(val) })
+		if err == nil {
+			break
+		}
+		if err != errRetry {
+			return err
+		}
+	}
+	// Pass the wakeup on in case it stood for more than one free slot
+	if q.Size() < q.Cap() {
+		wake(&q.spaceWaiters, q.space)
+	}
+	return nil
+}
+
+// Dequeue removes the oldest item, waiting for one until ctx is done.
+func (q *MPMCQueue[T]) Dequeue(ctx context.Context) (T, error) {
+	var val T
+	var ok bool
+	for {
+		if val, ok = q.TryDequeue(); ok {
+			break
+		}
+		err := park(ctx, &q.itemWaiters, q.items, func() bool {
+			val, ok = q.TryDequeue()
+			return ok
+		})
+		if err == nil {
+			break
+		}
+		if err != errRetry {
+			var zero T
+			return zero, err
+		}
+	}
+	// Pass the wakeup on in case it stood for more than one item
+	if !q.IsEmpty() {
+		wake(&q.itemWaiters, q.items)
+	}
+	return val, nil
+}
+
+// errRetry tells a blocking call that park was woken and should try again.
+var errRetry = errors.New("retry")
+
+// park waits on ch until it is signalled or ctx is done. It registers as a
+// waiter and then calls try once more, so a signal sent between the
+// caller's failed attempt and the registration is not lost. It returns nil
+// if try succeeded and errRetry after a wakeup.
+func park(ctx context.Context, waiters *atomic.Int32, ch chan struct{}, try func() bool) error {
+	waiters.Add(1)
+	defer waiters.Add(-1)
+	if try() {
+		return nil
+	}
+	select {
+	case <-ch:
+		return errRetry
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wake signals one parked goroutine, if there are any. The channel holds
+// one token per slot, so a full channel already has a wakeup pending for
+// every item or free slot.
+func wake(waiters *atomic.Int32, ch chan struct{}) {
+	if waiters.Load() > 0 {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Size returns an approximate number of items in the queue.
+func (q *MPMCQueue[T]) Size() int {
+	head := q.head.Load()
+	tail := q.tail.Load()
+	if n := int(tail - head); n < len(q.cells) {
+		return n
+	}
+	return len(q.cells)
+}
+
+func (q *MPMCQueue[T]) Cap() int {
+	return len(q.cells)
+}
+
+func (q *MPMCQueue[T]) IsEmpty() bool {
+	return q.Size() == 0
+}
@@ -0,0 +1,470 @@
+package typed
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMPMCQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		cap   int
+		steps []step
+	}{
+		{
+			name: "basic operations",
+			cap:  4,
+			steps: []step{
+				{"isEmpty", nil, true},
+				{"dequeue", nil, false},
+				{"enqueue", 1, true},
+				{"enqueue", 2, true},
+				{"size", nil, 2},
+				{"dequeue", nil, 1},
+				{"dequeue", nil, 2},
+				{"isEmpty", nil, true},
+			},
+		},
+		{
+			name: "full queue rejects enqueue",
+			cap:  2,
+			steps: []step{
+				{"enqueue", 1, true},
+				{"enqueue", 2, true},
+				{"enqueue", 3, false},
+				{"size", nil, 2},
+				{"dequeue", nil, 1},
+				{"enqueue", 3, true},
+				{"dequeue", nil, 2},
+				{"dequeue", nil, 3},
+				{"dequeue", nil, false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewMPMCQueue[int](tt.cap)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "enqueue":
+					if got := q.TryEnqueue(step.value.(int)); got != step.expected.(bool) {
+						t.Errorf("step %d: enqueue expected %v, got %v", i, step.expected, got)
+					}
+				case "dequeue":
+					val, ok := q.TryDequeue()
+					if step.expected != false {
+						if !ok || val != step.expected.(int) {
+							t.Errorf("step %d: dequeue expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+						}
+					} else if ok {
+						t.Errorf("step %d: dequeue expected to fail but succeeded with %v", i, val)
+					}
+				case "size":
+					if got := q.Size(); got != step.expected.(int) {
+						t.Errorf("step %d: size expected %v, got %v", i, step.expected, got)
+					}
+				case "isEmpty":
+					if got := q.IsEmpty(); got != step.expected.(bool) {
+						t.Errorf("step %d: isEmpty expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestMPMCQueue_InvalidCapacity(t *testing.T) {
+	for _, c := range []int{0, 1, 3, 12} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic for capacity %d", c)
+				}
+			}()
+			NewMPMCQueue[int](c)
+		}()
+	}
+}
+
+func TestMPMCQueue_Blocking(t *testing.T) {
+	q := NewMPMCQueue[int](2)
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		if err := q.Enqueue(ctx, i); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := q.Enqueue(timeout, 3); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded on full queue, got %v", err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		val, err := q.Dequeue(ctx)
+		if err != nil || val != i {
+			t.Errorf("expected %d, got %v (err=%v)", i, val, err)
+		}
+	}
+
+	cancelled, cancel2 := context.WithCancel(ctx)
+	cancel2()
+	if _, err := q.Dequeue(cancelled); err != context.Canceled {
+		t.Errorf("expected canceled on empty queue, got %v", err)
+	}
+}
+
+// TestMPMCQueue_IdleParks checks that Dequeue on an empty queue sleeps
+// instead of spinning, and still returns as soon as ctx is cancelled.
+func TestMPMCQueue_IdleParks(t *testing.T) {
+	q := NewMPMCQueue[int](4)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	const workers = 4
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			_, err := q.Dequeue(ctx)
+			errs <- err
+		}()
+	}
+
+	// Parked goroutines show up as blocked in select; spinning ones would
+	// be runnable or running
+	deadline := time.Now().Add(time.Second)
+	for {
+		buf := make([]byte, 1<<20)
+		stacks := string(buf[:runtime.Stack(buf, true)])
+		parked := 0
+		for _, g := range strings.Split(stacks, "\n\n") {
+			if strings.Contains(g, "MPMCQueue[...]).Dequeue") && strings.Contains(g, "[select") {
+				parked++
+			}
+		}
+		if parked == workers {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d parked workers, got %d", workers, parked)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	for w := 0; w < workers; w++ {
+		select {
+		case err := <-errs:
+			if err != context.Canceled {
+				t.Errorf("expected canceled, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Dequeue did not return after cancel")
+		}
+	}
+}
+
+// TestMPMCQueue_BlockingStress runs more blocked producers and consumers
+// than the queue has slots, so a lost wakeup would hang the test.
+func TestMPMCQueue_BlockingStress(t *testing.T) {
+	const (
+		goroutines = 8
+		perG       = 2000
+	)
+	q := NewMPMCQueue[int](2)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var sum atomic.Int64
+	for g := 0; g < goroutines; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= perG; i++ {
+				if err := q.Enqueue(ctx, i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perG; i++ {
+				val, err := q.Dequeue(ctx)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				sum.Add(int64(val))
+			}
+		}()
+	}
+	wg.Wait()
+	if want := int64(goroutines * perG * (perG + 1) / 2); sum.Load() != want {
+		t.Errorf("expected sum %d, got %d", want, sum.Load())
+	}
+}
+
+// TestMPMCQueue_Stress checks that every enqueued item is dequeued exactly
+// once and that each producer's items arrive in the order they were sent.
+func TestMPMCQueue_Stress(t *testing.T) {
+	const (
+		producers = 4
+		consumers = 4
+		perProd   = 5000
+	)
+	q := NewMPMCQueue[[2]int](16)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProd; i++ {
+				if err := q.Enqueue(ctx, [2]int{p, i}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(p)
+	}
+
+	results := make([][][2]int, consumers)
+	var received atomic.Int64
+	var cwg sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		cwg.Add(1)
+		go func(c int) {
+			defer cwg.Done()
+			for received.Load() < producers*perProd {
+				val, ok := q.TryDequeue()
+				if !ok {
+					runtime.Gosched()
+					continue
+				}
+				received.Add(1)
+				results[c] = append(results[c], val)
+			}
+		}(c)
+	}
+	wg.Wait()
+	cwg.Wait()
+
+	seen := make([][]bool, producers)
+	for p := range seen {
+		seen[p] = make([]bool, perProd)
+	}
+	for c, items := range results {
+		last := make([]int, producers)
+		for p := range last {
+			last[p] = -1
+		}
+		for _, item := range items {
+			p, i := item[0], item[1]
+			if seen[p][i] {
+				t.Fatalf("item %v dequeued twice", item)
+			}
+			seen[p][i] = true
+			if i <= last[p] {
+				t.Fatalf("consumer %d saw producer %d item %d after %d", c, p, i, last[p])
+			}
+			last[p] = i
+		}
+	}
+	for p := range seen {
+		for i, ok := range seen[p] {
+			if !ok {
+				t.Fatalf("item %v was never dequeued", [2]int{p, i})
+			}
+		}
+	}
+	if !q.IsEmpty() {
+		t.Errorf("expected queue to be empty, size is %d", q.Size())
+	}
+}
+
+type mpmcOp struct {
+	enqueue bool
+	val     int
+	ok      bool
+	call    int64
+	ret     int64
+}
+
+// TestMPMCQueue_Linearizable records short concurrent histories and checks
+// that the successful operations can be ordered into a sequence that a
+// plain Queue with the same capacity would produce.
+func TestMPMCQueue_Linearizable(t *testing.T) {
+	const (
+		capacity   = 2
+		goroutines = 3
+		opsPerG    = 4
+		rounds     = 200
+	)
+
+	for round := 0; round < rounds; round++ {
+		q := NewMPMCQueue[int](capacity)
+		var clock atomic.Int64
+		history := make([][]mpmcOp, goroutines)
+
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < opsPerG; i++ {
+					op := mpmcOp{enqueue: (g+i+round)%2 == 0}
+					op.call = clock.Add(1)
+					if op.enqueue {
+						op.val = g*opsPerG + i + 1
+						op.ok = q.TryEnqueue(op.val)
+					} else {
+						op.val, op.ok = q.TryDequeue()
+					}
+					op.ret = clock.Add(1)
+					history[g] = append(history[g], op)
+					runtime.Gosched()
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		var ops []mpmcOp
+		for _, h := range history {
+			for _, op := range h {
+				if op.ok {
+					ops = append(ops, op)
+				}
+			}
+		}
+		if !linearizeQueue(ops, make([]bool, len(ops)), nil, capacity) {
+			t.Fatalf("round %d: history is not linearizable: %+v", round, history)
+		}
+	}
+}
+
+// linearizeQueue searches for an order of ops that respects real time and
+// matches the results of applying them to a sequential Queue.
+func linearizeQueue(ops []mpmcOp, done []bool, state []int, capacity int) bool {
+	remaining := false
+	minRet := int64(1<<63 - 1)
+	for i, op := range ops {
+		if !done[i] {
+			remaining = true
+			if op.ret < minRet {
+				minRet = op.ret
+			}
+		}
+	}
+	if !remaining {
+		return true
+	}
+
+	for i, op := range ops {
+		if done[i] || op.call > minRet {
+			continue
+		}
+		next, ok := applyQueueOp(state, op, capacity)
+		if !ok {
+			continue
+		}
+		done[i] = true
+		if linearizeQueue(ops, done, next, capacity) {
+			return true
+		}
+		done[i] = false
+	}
+	return false
+}
+
+func applyQueueOp(state []int, op mpmcOp, capacity int) ([]int, bool) {
+	q := NewQueue[int]()
+	for _, v := range state {
+		q.Push(v)
+	}
+	if op.enqueue {
+		if q.Size() == capacity {
+			return nil, false
+		}
+		q.Push(op.val)
+	} else {
+		val, ok := q.Pop()
+		if !ok || val != op.val {
+			return nil, false
+		}
+	}
+	next := make([]int, 0, q.Size())
+	for !q.IsEmpty() {
+		val, _ := q.Pop()
+		next = append(next, val)
+	}
+	return next, true
+}
+
+func BenchmarkMPMCQueue(b *testing.B) {
+	q := NewMPMCQueue[int](1024)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for !q.TryEnqueue(1) {
+				runtime.Gosched()
+			}
+			for {
+				if _, ok := q.TryDequeue(); ok {
+					break
+				}
+				runtime.Gosched()
+			}
+		}
+	})
+}
+
+func BenchmarkMPMCQueue_MutexQueue(b *testing.B) {
+	var mu sync.Mutex
+	q := NewQueue[int]()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mu.Lock()
+			q.Push(1)
+			mu.Unlock()
+			mu.Lock()
+			q.Pop()
+			mu.Unlock()
+		}
+	})
+}
+
+// Example of using MPMCQueue
+func ExampleMPMCQueue() {
+	// Create a queue with room for 64 items
+	q := NewMPMCQueue[string](64)
+
+	// Non-blocking operations, safe from any goroutine
+	ok := q.TryEnqueue("job-1")  // false if the queue is full
+	job, found := q.TryDequeue() // job = "job-1", found = true
+
+	// Blocking operations wait until ctx is done
+	ctx := context.Background()
+	err := q.Enqueue(ctx, "job-2")
+	job, err = q.Dequeue(ctx) // job = "job-2"
+
+	// Prevent unused variable warnings in example
+	_, _, _, _ = ok, job, found, err
+}
@@ -1,9 +1,16 @@
 package typed
 
+import (
+	"math"
+	"time"
+)
+
 type LimitOptions struct {
-	ShrinkThresholdCap int     // Minimum cap to consider shrinking
-	ShrinkUsageRatio   float64 // Usage ratio (e.g., 0.25 = shrink if using <25%)
-	EnableAutoShrink   bool    // Control whether auto-shrink is enabled
+	ShrinkThresholdCap int          // Minimum cap to consider shrinking
+	ShrinkUsageRatio   float64      // Usage ratio (e.g., 0.25 = shrink if using <25%)
+	EnableAutoShrink   bool         // Control whether auto-shrink is enabled
+	ShrinkHeadroom     float64      // Spare room kept when shrinking, as a fraction of the size
+	ShrinkPolicy       ShrinkPolicy // Replaces the threshold and ratio check when set
 }
 
 func DefaultLimitOptions() LimitOptions {
@@ -11,6 +18,7 @@ func DefaultLimitOptions() LimitOptions {
 		ShrinkThresholdCap: 1024,
 		ShrinkUsageRatio:   0.25,
 		EnableAutoShrink:   true,
+		ShrinkHeadroom:     0.25,
 	}
 }
 
@@ -39,3 +47,214 @@ func WithAutoShrink(enabled bool) LimitOption {
 		o.EnableAutoShrink = enabled
 	}
 }
+
+// WithShrinkHeadroom sets how much room to keep above the current size when
+// shrinking, e.g. 0.5 shrinks a container holding 100 items to 150 slots.
+func WithShrinkHeadroom(ratio float64) LimitOption {
+	if ratio < 0 {
+		panic("Shrink headroom must not be negative")
+	}
+	return func(o *LimitOptions) {
+		o.ShrinkHeadroom = ratio
+	}
+}
+
+// WithShrinkPolicy decides when to shrink with p instead of the threshold
+// and ratio settings. Policies keep state, so each container needs its own.
+func WithShrinkPolicy(p ShrinkPolicy) LimitOption {
+	return func(o *LimitOptions) {
+		o.ShrinkPolicy = p
+	}
+}
+
+func (o LimitOptions) shouldShrink(size, capacity int) bool {
+	if !o.EnableAutoShrink {
+		return false
+	}
+	if o.ShrinkPolicy != nil {
+		return o.ShrinkPolicy.ShouldShrink(size, capacity)
+	}
+	return capacity > o.ShrinkThresholdCap &&
+		float64(size) < float64(capacity)*o.ShrinkUsageRatio
+}
+
+// shrinkTarget is the capacity to shrink a container holding size items to.
+func (o LimitOptions) shrinkTarget(size int) int {
+	return size + int(math.Ceil(float64(size)*o.ShrinkHeadroom))
+}
+
+func (o LimitOptions) resized(capacity int) {
+	if o.ShrinkPolicy != nil {
+		o.ShrinkPolicy.Resized(capacity)
+	}
+}
+
+// ShrinkPolicy decides when a container releases unused capacity.
+type ShrinkPolicy interface {
+	// ShouldShrink is called after every removal with the number of items
+	// and the current capacity.
+	ShouldShrink(size, capacity int) bool
+	// Resized is called whenever the container grows or shrinks.
+	Resized(capacity int)
+}
+
+type ratioShrinkPolicy struct {
+	minCap    int
+	low, high float64
+	armed     bool
+	capacity  int
+	rearm     int // size that arms the policy again after a shrink
+}
+
+// NewRatioShrinkPolicy shrinks once usage falls below low on a container
+// larger than minCap. After shrinking it waits until the container grows,
+// or holds more than high of its capacity before the shrink, so a
+// workload hovering around low does not reallocate over and over.
+func NewRatioShrinkPolicy(minCap int, low, high float64) ShrinkPolicy {
+	if low < 0 || high > 1 || low > high {
+		panic("Shrink ratios must satisfy 0 <= low <= high <= 1")
+	}
+	return &ratioShrinkPolicy{minCap: minCap, low: low, high: high, armed: true}
+}
+
+func (p *ratioShrinkPolicy) ShouldShrink(size, capacity int) bool {
+	if !p.armed {
+		p.armed = size > p.rearm
+		return false
+	}
+	usage := float64(size) / float64(max(capacity, 1))
+	return capacity > p.minCap && usage < p.low
+}
+
+func (p *ratioShrinkPolicy) Resized(capacity int) {
+	// The shrunk container is nearly full, so usage against the new
+	// capacity says nothing; measure against the old one instead.
+	p.armed = capacity > p.capacity
+	if !p.armed {
+		p.rearm = int(float64(p.capacity) * p.high)
+	}
+	p.capacity = capacity
+}
+
+type cooldownShrinkPolicy struct {
+	inner ShrinkPolicy
+	ops   int
+	left  int
+}
+
+// NewCooldownShrinkPolicy wraps inner and ignores it for the first ops
+// removals after every resize.
+func NewCooldownShrinkPolicy(inner ShrinkPolicy, ops int) ShrinkPolicy {
+	if ops < 0 {
+		panic("Cooldown operations must not be negative")
+	}
+	return &cooldownShrinkPolicy{inner: inner, ops: ops}
+}
+
+func (p *cooldownShrinkPolicy) ShouldShrink(size, capacity int) bool {
+	if p.left > 0 {
+		p.left--
+		return false
+	}
+	return p.inner.ShouldShrink(size, capacity)
+}
+
+func (p *cooldownShrinkPolicy) Resized(capacity int) {
+	p.left = p.ops
+	p.inner.Resized(capacity)
+}
+
+type idleShrinkPolicy struct {
+	inner ShrinkPolicy
+	idle  time.Duration
+	now   func() time.Time
+	since time.Time
+}
+
+// NewIdleShrinkPolicy wraps inner and only shrinks once inner has asked to
+// shrink on every removal for at least idle. A nil now uses time.Now.
+func NewIdleShrinkPolicy(inner ShrinkPolicy, idle time.Duration, now func() time.Time) ShrinkPolicy {
+	if now == nil {
+		now = time.Now
+	}
+	return &idleShrinkPolicy{inner: inner, idle: idle, now: now}
+}
+
+func (p *idleShrinkPolicy) ShouldShrink(size, capacity int) bool {
+	if !p.inner.ShouldShrink(size, capacity) {
+		p.since = time.Time{}
+		return false
+	}
+	now := p.now()
+	if p.since.IsZero() {
+		p.since = now
+	}
+	return now.Sub(p.since) >= p.idle
+}
+
+func (p *idleShrinkPolicy) Resized(capacity int) {
+	p.since = time.Time{}
+	p.inner.Resized(capacity)
+}
+
+type GrowthOptions struct {
+	InitialCapacity int     // Capacity allocated up front, and the floor when shrinking
+	GrowthFactor    float64 // Multiplier applied to the capacity when full
+	MaxGrowthStep   int     // Upper bound on slots added per growth, 0 for no limit
+}
+
+func DefaultGrowthOptions() GrowthOptions {
+	return GrowthOptions{
+		InitialCapacity: 4,
+		GrowthFactor:    2,
+	}
+}
+
+type GrowthOption func(*GrowthOptions)
+
+func WithInitialCapacity(cap int) GrowthOption {
+	if cap < 0 {
+		panic("Initial capacity must not be negative")
+	}
+	return func(o *GrowthOptions) {
+		o.InitialCapacity = cap
+	}
+}
+
+func WithGrowthFactor(factor float64) GrowthOption {
+	if factor <= 1 {
+		panic("Growth factor must be greater than 1")
+	}
+	return func(o *GrowthOptions) {
+		o.GrowthFactor = factor
+	}
+}
+
+func WithMaxGrowthStep(step int) GrowthOption {
+	if step <= 0 {
+		panic("Max growth step must be greater than 0")
+	}
+	return func(o *GrowthOptions) {
+		o.MaxGrowthStep = step
+	}
+}
+
+// minGrowCap is the capacity allocated when growing from nothing without
+// an initial capacity.
+const minGrowCap = 4
+
+// nextCap returns the capacity to grow to from cur so that at least need
+// items fit.
+func (o GrowthOptions) nextCap(cur, need int) int {
+	next := int(float64(cur) * o.GrowthFactor)
+	if next <= cur {
+		next = cur + 1
+	}
+	if cur == 0 {
+		next = max(o.InitialCapacity, minGrowCap)
+	}
+	if o.MaxGrowthStep > 0 && next-cur > o.MaxGrowthStep {
+		next = cur + o.MaxGrowthStep
+	}
+	return max(next, need)
+}
@@ -0,0 +1,214 @@
+package typed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShrinkPolicies(t *testing.T) {
+	type step struct {
+		op       string
+		size     int
+		capacity int
+		expected bool
+	}
+
+	var now time.Time
+	clock := func() time.Time { return now }
+
+	tests := []struct {
+		name   string
+		policy func() ShrinkPolicy
+		steps  []step
+	}{
+		{
+			name:   "ratio below minimum capacity",
+			policy: func() ShrinkPolicy { return NewRatioShrinkPolicy(64, 0.25, 0.5) },
+			steps: []step{
+				{"check", 1, 64, false},
+				{"check", 15, 128, true},
+			},
+		},
+		{
+			name:   "ratio hysteresis after shrink",
+			policy: func() ShrinkPolicy { return NewRatioShrinkPolicy(0, 0.25, 0.5) },
+			steps: []step{
+				{"resize", 0, 100, false},
+				{"check", 10, 100, true},
+				{"resize", 0, 13, false},
+				{"check", 3, 13, false}, // still below low, but not re-armed
+				{"check", 7, 13, false}, // 54% of the new capacity does not re-arm
+				{"check", 3, 13, false},
+			},
+		},
+		{
+			name:   "ratio re-arms above high of the old capacity",
+			policy: func() ShrinkPolicy { return NewRatioShrinkPolicy(0, 0.25, 0.5) },
+			steps: []step{
+				{"resize", 0, 100, false},
+				{"check", 20, 100, true},
+				{"resize", 0, 60, false},
+				{"check", 50, 60, false}, // 50% of 100, not above high yet
+				{"check", 51, 60, false}, // re-arms
+				{"check", 10, 60, true},
+			},
+		},
+		{
+			name:   "ratio re-arms on growth",
+			policy: func() ShrinkPolicy { return NewRatioShrinkPolicy(0, 0.25, 0.9) },
+			steps: []step{
+				{"resize", 0, 8, false},
+				{"resize", 0, 4, false},
+				{"check", 0, 4, false},
+				{"resize", 0, 16, false},
+				{"check", 1, 16, true},
+			},
+		},
+		{
+			name: "cooldown after resize",
+			policy: func() ShrinkPolicy {
+				return NewCooldownShrinkPolicy(NewRatioShrinkPolicy(0, 0.25, 0.25), 2)
+			},
+			steps: []step{
+				{"resize", 0, 100, false},
+				{"check", 1, 100, false},
+				{"check", 1, 100, false},
+				{"check", 1, 100, true},
+			},
+		},
+		{
+			name: "idle time",
+			policy: func() ShrinkPolicy {
+				return NewIdleShrinkPolicy(NewRatioShrinkPolicy(0, 0.25, 0.25), time.Second, clock)
+			},
+			steps: []step{
+				{"check", 1, 100, false},
+				{"advance", 500, 0, false},
+				{"check", 1, 100, false},
+				{"check", 50, 100, false}, // busy again, timer restarts
+				{"check", 1, 100, false},
+				{"advance", 999, 0, false},
+				{"check", 1, 100, false},
+				{"advance", 1, 0, false},
+				{"check", 1, 100, true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.policy()
+			now = time.Unix(0, 0)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "check":
+					if got := p.ShouldShrink(step.size, step.capacity); got != step.expected {
+						t.Errorf("step %d: shouldShrink(%d, %d) expected %v, got %v",
+							i, step.size, step.capacity, step.expected, got)
+					}
+				case "resize":
+					p.Resized(step.capacity)
+				case "advance":
+					now = now.Add(time.Duration(step.size) * time.Millisecond)
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+type countingShrinkPolicy struct {
+	ShrinkPolicy
+	resizes int
+}
+
+func (p *countingShrinkPolicy) Resized(capacity int) {
+	p.resizes++
+	p.ShrinkPolicy.Resized(capacity)
+}
+
+// TestRatioShrinkPolicy_Oscillation drains a queue once and then swings
+// its size back and forth below the low mark. Only the first drain may
+// shrink; the swings fit in the shrunk capacity and must not reallocate.
+func TestRatioShrinkPolicy_Oscillation(t *testing.T) {
+	q := NewQueue[int](WithQueueLimitOptions(
+		WithShrinkPolicy(NewRatioShrinkPolicy(0, 0.25, 0.5)),
+	))
+	for q.Size() < 1000 {
+		q.Push(0)
+	}
+	before := q.Stats()
+
+	for round := 0; round < 20; round++ {
+		for q.Size() > 20 {
+			q.Pop()
+		}
+		for q.Size() < 200 {
+			q.Push(0)
+		}
+	}
+	after := q.Stats()
+	if shrinks := after.Shrinks - before.Shrinks; shrinks > 1 {
+		t.Errorf("expected at most 1 shrink, got %d", shrinks)
+	}
+	if grows := after.Grows - before.Grows; grows != 0 {
+		t.Errorf("expected no grows, got %d", grows)
+	}
+}
+
+// TestShrinkPolicy_NoThrash fills and drains a queue in bursts and checks
+// that a cooldown with headroom reallocates far less than a bare ratio.
+func TestShrinkPolicy_NoThrash(t *testing.T) {
+	resizes := func(p ShrinkPolicy, headroom float64) int {
+		counter := &countingShrinkPolicy{ShrinkPolicy: p}
+		q := NewQueue[int](WithQueueLimitOptions(
+			WithShrinkPolicy(counter),
+			WithShrinkHeadroom(headroom),
+		))
+		for round := 0; round < 50; round++ {
+			for q.Size() < 40 {
+				q.Push(round)
+			}
+			for q.Size() > 8 {
+				q.Pop()
+			}
+		}
+		return counter.resizes
+	}
+
+	bare := resizes(NewRatioShrinkPolicy(16, 0.25, 0.25), 0)
+	tuned := resizes(NewCooldownShrinkPolicy(NewRatioShrinkPolicy(16, 0.25, 0.5), 100), 0.5)
+	if tuned*2 > bare {
+		t.Errorf("expected far fewer resizes with cooldown and headroom, got %d vs %d", tuned, bare)
+	}
+}
+
+func TestShrinkHeadroom(t *testing.T) {
+	tests := []struct {
+		name     string
+		headroom float64
+		expected int
+	}{
+		{"no headroom", 0, 10},
+		{"default headroom", 0.25, 13},
+		{"half headroom", 0.5, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStack[int](WithStackLimitOptions(WithShrinkHeadroom(tt.headroom)))
+			for i := 0; i < 100; i++ {
+				s.Push(i)
+			}
+			for s.Len() > 10 {
+				s.Pop()
+			}
+			s.Shrink()
+			if s.Cap() != tt.expected {
+				t.Errorf("expected capacity %d, got %d", tt.expected, s.Cap())
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package typed
+
+import "sync/atomic"
+
+// PStack is a persistent stack: Push and Pop return a new version and leave
+// the receiver unchanged. Versions share their common tail, so every
+// operation is O(1), and they can be used from many goroutines without
+// locking. The zero value is an empty stack.
+type PStack[T any] struct {
+	head *pnode[T]
+	size int
+}
+
+type pnode[T any] struct {
+	val  T
+	next *pnode[T]
+}
+
+// Push returns a new stack with val on top.
+func (s PStack[T]) Push(val T) PStack[T] {
+	return PStack[T]{head: &pnode[T]{val: val, next: s.head}, size: s.size + 1}
+}
+
+// Pop returns the top item and the stack without it.
+func (s PStack[T]) Pop() (T, PStack[T], bool) {
+	if s.head == nil {
+		var zero T
+		return zero, s, false
+	}
+	return s.head.val, PStack[T]{head: s.head.next, size: s.size - 1}, true
+}
+
+func (s PStack[T]) Peek() (T, bool) {
+	if s.head == nil {
+		var zero T
+		return zero, false
+	}
+	return s.head.val, true
+}
+
+func (s PStack[T]) Len() int {
+	return s.size
+}
+
+func (s PStack[T]) IsEmpty() bool {
+	return s.size == 0
+}
+
+// Items returns the items from top to bottom.
+func (s PStack[T]) Items() []T {
+	items := make([]T, 0, s.size)
+	for n := s.head; n != nil; n = n.next {
+		items = append(items, n.val)
+	}
+	return items
+}
+
+// PQueue is a persistent FIFO queue: Push and Pop return a new version and
+// leave the receiver unchanged. It is a banker's queue whose front is a
+// lazy, memoized stream, so operations stay amortized O(1) even when old
+// versions are reused, and versions can be used from many goroutines
+// without locking. The zero value is an empty queue.
+type PQueue[T any] struct {
+	front *pstream[T] // nil or a stream of flen items
+	flen  int
+	rear  PStack[T] // newest item on top
+}
+
+// pstream is a lazily evaluated list cell. It is forced at most once per
+// winner; concurrent callers may evaluate the thunk too, but only the first
+// result is kept, so all readers see the same cells.
+type pstream[T any] struct {
+	cell  atomic.Pointer[pcell[T]]
+	thunk atomic.Pointer[func() *pcell[T]]
+}
+
+// pcell is a stream cell; the end of the stream has a nil next.
+type pcell[T any] struct {
+	val  T
+	next *pstream[T]
+}
+
+func lazyStream[T any](fn func() *pcell[T]) *pstream[T] {
+	s := &pstream[T]{}
+	s.thunk.Store(&fn)
+	return s
+}
+
+func forcedStream[T any](c *pcell[T]) *pstream[T] {
+	s := &pstream[T]{}
+	s.cell.Store(c)
+	return s
+}
+
+func (s *pstream[T]) force() *pcell[T] {
+	if c := s.cell.Load(); c != nil {
+		return c
+	}
+	fn := s.thunk.Load()
+	if fn == nil {
+		// Another goroutine stored the cell before clearing the thunk
+		return s.cell.Load()
+	}
+	if s.cell.CompareAndSwap(nil, (*fn)()) {
+		s.thunk.Store(nil) // release what the thunk captured
+	}
+	return s.cell.Load()
+}
+
+// appendReversed lazily returns f followed by the items of r from bottom
+// to top. The reversal runs only once the stream reaches the end of f.
+func appendReversed[T any](f *pstream[T], r PStack[T]) *pstream[T] {
+	return lazyStream(func() *pcell[T] {
+		if f != nil {
+			if c := f.force(); c.next != nil {
+				return &pcell[T]{val: c.val, next: appendReversed(c.next, r)}
+			}
+		}
+		c := &pcell[T]{}
+		for n := r.head; n != nil; n = n.next {
+			c = &pcell[T]{val: n.val, next: forcedStream(c)}
+		}
+		return c
+	})
+}
+
+// Push returns a new queue with val at the back.
+func (q PQueue[T]) Push(val T) PQueue[T] {
+	q.rear = q.rear.Push(val)
+	return q.check()
+}
+
+// Pop returns the front item and the queue without it.
+func (q PQueue[T]) Pop() (T, PQueue[T], bool) {
+	if q.flen == 0 {
+		var zero T
+		return zero, q, false
+	}
+	c := q.front.force()
+	q.front = c.next
+	q.flen--
+	return c.val, q.check(), true
+}
+
+func (q PQueue[T]) Peek() (T, bool) {
+	if q.flen == 0 {
+		var zero T
+		return zero, false
+	}
+	return q.front.force().val, true
+}
+
+func (q PQueue[T]) Len() int {
+	return q.flen + q.rear.Len()
+}
+
+func (q PQueue[T]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// Items returns the items from front to back.
+func (q PQueue[T]) Items() []T {
+	items := make([]T, 0, q.Len())
+	for s, i := q.front, 0; i < q.flen; i++ {
+		c := s.force()
+		items = append(items, c.val)
+		s = c.next
+	}
+	rear := q.rear.Items()
+	for i := len(rear) - 1; i >= 0; i-- {
+		items = append(items, rear[i])
+	}
+	return items
+}
+
+// check keeps the rear no longer than the front by scheduling a lazy
+// rotation of the rear onto the front.
+func (q PQueue[T]) check() PQueue[T] {
+	if q.rear.Len() <= q.flen {
+		return q
+	}
+	return PQueue[T]{
+		front: appendReversed(q.front, q.rear),
+		flen:  q.flen + q.rear.Len(),
+	}
+}
@@ -0,0 +1,208 @@
+package typed
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPStack(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	steps := []step{
+		{"pop", nil, nil},
+		{"push", 1, []int{1}},
+		{"push", 2, []int{2, 1}},
+		{"push", 3, []int{3, 2, 1}},
+		{"peek", nil, 3},
+		{"pop", nil, 3},
+		{"pop", nil, 2},
+		{"push", 4, []int{4, 1}},
+		{"len", nil, 2},
+	}
+
+	var s PStack[int]
+	versions := []PStack[int]{s}
+	for i, step := range steps {
+		switch step.op {
+		case "push":
+			s = s.Push(step.value.(int))
+			if got := s.Items(); !reflect.DeepEqual(got, step.expected) {
+				t.Errorf("step %d: push expected %v, got %v", i, step.expected, got)
+			}
+		case "pop":
+			val, next, ok := s.Pop()
+			if step.expected == nil {
+				if ok {
+					t.Errorf("step %d: pop expected to fail, got %v", i, val)
+				}
+			} else if !ok || val != step.expected.(int) {
+				t.Errorf("step %d: pop expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+			}
+			s = next
+		case "peek":
+			if val, ok := s.Peek(); !ok || val != step.expected.(int) {
+				t.Errorf("step %d: peek expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+			}
+		case "len":
+			if got := s.Len(); got != step.expected.(int) {
+				t.Errorf("step %d: len expected %v, got %v", i, step.expected, got)
+			}
+		default:
+			t.Fatalf("step %d: unknown op %s", i, step.op)
+		}
+		versions = append(versions, s)
+	}
+
+	// Earlier versions are unchanged
+	if got := versions[4].Items(); !reflect.DeepEqual(got, []int{3, 2, 1}) {
+		t.Errorf("expected old version [3 2 1], got %v", got)
+	}
+	if !versions[0].IsEmpty() {
+		t.Errorf("expected first version to stay empty")
+	}
+}
+
+func TestPQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	steps := []step{
+		{"pop", nil, nil},
+		{"push", 1, []int{1}},
+		{"push", 2, []int{1, 2}},
+		{"push", 3, []int{1, 2, 3}},
+		{"peek", nil, 1},
+		{"pop", nil, 1},
+		{"push", 4, []int{2, 3, 4}},
+		{"pop", nil, 2},
+		{"pop", nil, 3},
+		{"pop", nil, 4},
+		{"pop", nil, nil},
+		{"len", nil, 0},
+	}
+
+	var q PQueue[int]
+	versions := []PQueue[int]{q}
+	for i, step := range steps {
+		switch step.op {
+		case "push":
+			q = q.Push(step.value.(int))
+			if got := q.Items(); !reflect.DeepEqual(got, step.expected) {
+				t.Errorf("step %d: push expected %v, got %v", i, step.expected, got)
+			}
+		case "pop":
+			val, next, ok := q.Pop()
+			if step.expected == nil {
+				if ok {
+					t.Errorf("step %d: pop expected to fail, got %v", i, val)
+				}
+			} else if !ok || val != step.expected.(int) {
+				t.Errorf("step %d: pop expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+			}
+			q = next
+		case "peek":
+			if val, ok := q.Peek(); !ok || val != step.expected.(int) {
+				t.Errorf("step %d: peek expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+			}
+		case "len":
+			if got := q.Len(); got != step.expected.(int) {
+				t.Errorf("step %d: len expected %v, got %v", i, step.expected, got)
+			}
+		default:
+			t.Fatalf("step %d: unknown op %s", i, step.op)
+		}
+		versions = append(versions, q)
+	}
+
+	// Earlier versions are unchanged
+	if got := versions[7].Items(); !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Errorf("expected old version [2 3 4], got %v", got)
+	}
+}
+
+func TestPQueue_Versions(t *testing.T) {
+	// Branch from random old versions and compare each against a slice model
+	r := rand.New(rand.NewSource(1))
+	queues := []PQueue[int]{{}}
+	models := [][]int{nil}
+
+	for op := 0; op < 5000; op++ {
+		i := r.Intn(len(queues))
+		q, model := queues[i], models[i]
+		if r.Intn(3) > 0 || len(model) == 0 {
+			q = q.Push(op)
+			model = append(model[:len(model):len(model)], op)
+		} else {
+			val, next, ok := q.Pop()
+			if !ok || val != model[0] {
+				t.Fatalf("op %d: pop expected %v, got %v (ok=%v)", op, model[0], val, ok)
+			}
+			q, model = next, model[1:]
+		}
+		queues, models = append(queues, q), append(models, model)
+	}
+
+	for i, q := range queues {
+		if got := q.Items(); len(got) != len(models[i]) || (len(got) > 0 && !reflect.DeepEqual(got, models[i])) {
+			t.Fatalf("version %d: expected %v, got %v", i, models[i], got)
+		}
+	}
+}
+
+func TestPQueue_Concurrent(t *testing.T) {
+	var q PQueue[int]
+	for i := 0; i < 1000; i++ {
+		q = q.Push(i)
+	}
+
+	// Every goroutine drains the same shared version, forcing the same
+	// lazy cells at the same time
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := q
+			for i := 0; i < 1000; i++ {
+				val, next, ok := v.Pop()
+				if !ok || val != i {
+					t.Errorf("pop expected %v, got %v (ok=%v)", i, val, ok)
+					return
+				}
+				v = next
+			}
+		}()
+	}
+	wg.Wait()
+
+	if q.Len() != 1000 {
+		t.Errorf("expected shared version to keep 1000 items, got %d", q.Len())
+	}
+}
+
+// Example of using PStack and PQueue
+func ExamplePQueue() {
+	// The zero value is an empty queue
+	var q PQueue[string]
+	q = q.Push("a").Push("b")
+
+	// Pop returns a new version; q still holds both items
+	first, rest, ok := q.Pop() // "a", [b]
+
+	// Stacks share their tails between versions
+	var s PStack[int]
+	base := s.Push(1)
+	left, right := base.Push(2), base.Push(3)
+
+	// Prevent unused variable warnings in example
+	_, _, _, _, _ = first, rest, ok, left, right
+}
@@ -1,14 +1,18 @@
 package typed
 
 type QueueOptions struct {
-	LimitOptions // Shared shrink options
+	LimitOptions      // Shared shrink options
+	GrowthOptions     // Shared growth options
+	ResizeHooks       // Shared resize callbacks
+	SegmentSize   int // Block size of the segmented backend, 0 for a contiguous ring
 }
 
 type QueueOption func(*QueueOptions)
 
 func defaultQueueOptions() QueueOptions {
 	return QueueOptions{
-		LimitOptions: DefaultLimitOptions(),
+		LimitOptions:  DefaultLimitOptions(),
+		GrowthOptions: DefaultGrowthOptions(),
 	}
 }
 
@@ -20,12 +24,45 @@ func WithQueueLimitOptions(limitOpts ...LimitOption) QueueOption {
 	}
 }
 
+func WithQueueGrowthOptions(growthOpts ...GrowthOption) QueueOption {
+	return func(qo *QueueOptions) {
+		for _, opt := range growthOpts {
+			opt(&qo.GrowthOptions)
+		}
+	}
+}
+
+func WithQueueResizeHooks(hooks ...ResizeHook) QueueOption {
+	return func(qo *QueueOptions) {
+		for _, hook := range hooks {
+			hook(&qo.ResizeHooks)
+		}
+	}
+}
+
+// WithQueueSegmentSize stores the queue as a list of fixed-size blocks
+// instead of one ring buffer. Growing allocates a single block and
+// shrinking releases empty blocks, so Push and Pop never copy the queue.
+// LimitOptions and GrowthOptions do not apply to the segmented backend.
+func WithQueueSegmentSize(size int) QueueOption {
+	if size <= 0 {
+		panic("Segment size must be greater than 0")
+	}
+	return func(qo *QueueOptions) {
+		qo.SegmentSize = size
+	}
+}
+
 type Queue[T any] struct {
 	queue []T
 	start int
 	end   int
 	size  int
+	seg   *segments[T]
 	opts  QueueOptions
+	stats Stats
+	// shared is set while a Snapshot may still read queue
+	shared bool
 }
 
 func NewQueue[T any](opts ...QueueOption) *Queue[T] {
@@ -33,19 +70,33 @@ func NewQueue[T any](opts ...QueueOption) *Queue[T] {
 	for _, opt := range opts {
 		opt(&o)
 	}
+	if o.SegmentSize > 0 {
+		q := &Queue[T]{opts: o}
+		q.seg = newSegments[T](o.SegmentSize, &q.stats, o.ResizeHooks)
+		return q
+	}
 	return &Queue[T]{
-		queue: make([]T, 2),
+		queue: make([]T, o.InitialCapacity),
 		opts:  o,
 	}
 }
 
 func (q *Queue[T]) Push(val T) {
-	if q.size+1 == len(q.queue) || len(q.queue) == 0 {
+	if q.seg != nil {
+		q.seg.pushBack(val)
+		q.size++
+		q.stats.recordPush(q.size)
+		return
+	}
+	if q.size == len(q.queue) {
 		q.resize()
+	} else if q.shared {
+		q.unshare()
 	}
 	q.queue[q.end] = val
 	q.size++
 	q.end = (q.end + 1) % len(q.queue)
+	q.stats.recordPush(q.size)
 }
 
 func (q *Queue[T]) Pop() (T, bool) {
@@ -53,8 +104,15 @@ func (q *Queue[T]) Pop() (T, bool) {
 	if q.IsEmpty() {
 		return zero, false
 	}
+	q.stats.recordPop()
+	if q.seg != nil {
+		q.size--
+		return q.seg.popFront()
+	}
 	val := q.queue[q.start]
-	q.queue[q.start] = zero
+	if !q.shared {
+		q.queue[q.start] = zero
+	}
 	q.start = (q.start + 1) % len(q.queue)
 	q.size--
 	if q.shouldShrink() {
@@ -68,6 +126,9 @@ func (q *Queue[T]) Peek() (T, bool) {
 	if q.IsEmpty() {
 		return zero, false
 	}
+	if q.seg != nil {
+		return q.seg.front()
+	}
 	return q.queue[q.start], true
 }
 
@@ -79,14 +140,45 @@ func (q *Queue[T]) Size() int {
 	return q.size
 }
 
+// Stats returns usage counters for the queue.
+func (q *Queue[T]) Stats() Stats {
+	return q.stats
+}
+
 func (q *Queue[T]) Cap() int {
+	if q.seg != nil {
+		return q.seg.cap()
+	}
 	return cap(q.queue)
 }
 
+// Reserve makes room for at least n items, so the queue does not grow
+// again until it holds more than n. Auto-shrink may still release the
+// space once usage drops.
+func (q *Queue[T]) Reserve(n int) {
+	if q.seg != nil {
+		q.seg.reserve(n)
+		return
+	}
+	if n > len(q.queue) {
+		q.realloc(n)
+	}
+}
+
 func (q *Queue[T]) Reset() {
-	var zero T
-	for i := 0; i < q.size; i++ {
-		q.queue[(q.start+i)%len(q.queue)] = zero
+	if q.seg != nil {
+		q.seg.reset()
+		q.size = 0
+		return
+	}
+	if q.shared {
+		q.queue = make([]T, len(q.queue))
+		q.shared = false
+	} else {
+		var zero T
+		for i := 0; i < q.size; i++ {
+			q.queue[(q.start+i)%len(q.queue)] = zero
+		}
 	}
 	q.start = 0
 	q.end = 0
@@ -97,29 +189,53 @@ func (q *Queue[T]) Reset() {
 }
 
 func (q *Queue[T]) shouldShrink() bool {
-	return q.opts.EnableAutoShrink &&
-		cap(q.queue) > q.opts.ShrinkThresholdCap &&
-		float64(q.size) < float64(cap(q.queue))*q.opts.ShrinkUsageRatio
+	return q.opts.shouldShrink(q.size, cap(q.queue))
+}
+
+func (q *Queue[T]) resize() {
+	q.realloc(q.opts.nextCap(len(q.queue), q.size+1))
 }
 
 func (q *Queue[T]) shrink() {
-	newQueue := make([]T, q.size)
-	for i := 0; i < q.size; i++ {
-		newQueue[i] = q.queue[(q.start+i)%len(q.queue)]
+	if target := max(q.opts.shrinkTarget(q.size), q.opts.InitialCapacity); target < len(q.queue) {
+		q.realloc(target)
 	}
-	q.queue = newQueue
-	q.start = 0
-	q.end = q.size
 }
 
-func (q *Queue[T]) resize() {
+// Snapshot returns a read-only view of the queue. The queue copies its
+// items on the next change instead of writing over the snapshot. With the
+// segmented backend the snapshot copies the items up front.
+func (q *Queue[T]) Snapshot() Snapshot[T] {
+	if q.seg != nil {
+		return Snapshot[T]{data: q.seg.appendTo(make([]T, 0, q.size)), size: q.size}
+	}
+	q.shared = q.shared || q.size > 0
+	return Snapshot[T]{data: q.queue, start: q.start, size: q.size}
+}
+
+// unshare gives the queue its own copy of the items held by a Snapshot.
+func (q *Queue[T]) unshare() {
+	q.moveTo(len(q.queue))
+}
+
+// realloc moves the items to the front of a new slice of length newCap.
+func (q *Queue[T]) realloc(newCap int) {
 	oldCap := len(q.queue)
-	newCap := (oldCap + 1) * 2
+	q.moveTo(newCap)
+	recordResize[T](&q.stats, q.opts.ResizeHooks, oldCap, newCap, q.size)
+	q.opts.resized(newCap)
+}
+
+func (q *Queue[T]) moveTo(newCap int) {
 	newQueue := make([]T, newCap)
 	for i := 0; i < q.size; i++ {
-		newQueue[i] = q.queue[(q.start+i)%oldCap]
+		newQueue[i] = q.queue[(q.start+i)%len(q.queue)]
 	}
 	q.queue = newQueue
 	q.start = 0
-	q.end = q.size
+	q.end = 0
+	if q.size < newCap {
+		q.end = q.size
+	}
+	q.shared = false
 }
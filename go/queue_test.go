@@ -211,6 +211,126 @@ func TestQueue_Options(t *testing.T) {
 	}
 }
 
+// TestQueue_ShrinkThenPush verifies that pushes after an exact-size shrink keep FIFO order
+func TestQueue_ShrinkThenPush(t *testing.T) {
+	q := NewQueue[int](WithQueueLimitOptions(WithShrinkThresholdCap(4)))
+
+	next, want := 0, 0
+	for round := 0; round < 200; round++ {
+		n := (round * 7919) % 23
+		if round%2 == 0 {
+			for i := 0; i < n; i++ {
+				q.Push(next)
+				next++
+			}
+			continue
+		}
+		for i := 0; i < n; i++ {
+			val, ok := q.Pop()
+			if !ok {
+				break
+			}
+			if val != want {
+				t.Fatalf("round %d: expected %d, got %d", round, want, val)
+			}
+			want++
+		}
+	}
+}
+
+func TestQueue_Segmented(t *testing.T) {
+	q := NewQueue[int](WithQueueSegmentSize(4))
+
+	next, want := 0, 0
+	for round := 0; round < 200; round++ {
+		n := (round * 7919) % 23
+		if round%2 == 0 {
+			for i := 0; i < n; i++ {
+				q.Push(next)
+				next++
+			}
+			continue
+		}
+		for i := 0; i < n; i++ {
+			val, ok := q.Pop()
+			if !ok {
+				break
+			}
+			if val != want {
+				t.Fatalf("round %d: expected %d, got %d", round, want, val)
+			}
+			want++
+		}
+		if peek, ok := q.Peek(); ok && peek != want {
+			t.Fatalf("round %d: peek expected %d, got %d", round, want, peek)
+		}
+	}
+	if q.Size() != next-want {
+		t.Errorf("expected size %d, got %d", next-want, q.Size())
+	}
+
+	for i := 0; i < 100; i++ {
+		q.Push(i)
+	}
+	grown := q.Cap()
+	for !q.IsEmpty() {
+		q.Pop()
+	}
+	if q.Cap() >= grown || q.Cap() > (1+maxFreeSegments)*4 {
+		t.Errorf("expected empty blocks to be released, cap went from %d to %d", grown, q.Cap())
+	}
+
+	q.Push(1)
+	q.Reset()
+	if !q.IsEmpty() || q.Size() != 0 {
+		t.Errorf("expected queue to be empty after reset")
+	}
+	q.Push(7)
+	if val, ok := q.Pop(); !ok || val != 7 {
+		t.Errorf("expected 7 after reset, got %v (ok=%v)", val, ok)
+	}
+
+	q.Reserve(40)
+	reserved := q.Cap()
+	for i := 0; i < 40; i++ {
+		q.Push(i)
+	}
+	if reserved < 40 || q.Cap() != reserved {
+		t.Errorf("expected reserve to cover 40 pushes, cap went from %d to %d", reserved, q.Cap())
+	}
+}
+
+func TestQueue_Growth(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []GrowthOption
+		reserve  int
+		push     int
+		expected int
+	}{
+		{"initial capacity", []GrowthOption{WithInitialCapacity(16)}, 0, 16, 16},
+		{"growth factor", []GrowthOption{WithInitialCapacity(16), WithGrowthFactor(1.5)}, 0, 17, 24},
+		{"max growth step", []GrowthOption{WithInitialCapacity(16), WithMaxGrowthStep(4)}, 0, 21, 24},
+		{"reserve", nil, 100, 100, 100},
+		{"reserve below capacity", []GrowthOption{WithInitialCapacity(16)}, 8, 10, 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue[int](WithQueueGrowthOptions(tt.opts...))
+			if tt.reserve > 0 {
+				q.Reserve(tt.reserve)
+			}
+			for i := 0; i < tt.push; i++ {
+				q.Push(i)
+			}
+			if got := q.Cap(); got != tt.expected {
+				t.Errorf("expected capacity %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
 // Example of using Queue
 func ExampleQueue() {
 	// Create a new queue of integers
@@ -0,0 +1,60 @@
+package typed
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// Records are the unit of every on-disk log in this package:
+//
+//	length uint32 | crc32 uint32 | kind byte | id uint64 | payload
+//
+// length covers kind, id and payload, and the checksum is taken over the
+// same bytes. A record that is cut short or fails its checksum marks the
+// point where a write was interrupted.
+const (
+	recordHeaderSize = 8
+	recordFixedSize  = 1 + 8
+)
+
+var errTornRecord = errors.New("typed: torn or corrupt record")
+
+type record struct {
+	kind    byte
+	id      uint64
+	payload []byte
+}
+
+func appendRecord(dst []byte, kind byte, id uint64, payload []byte) []byte {
+	n := recordFixedSize + len(payload)
+	start := len(dst)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(n))
+	dst = binary.LittleEndian.AppendUint32(dst, 0)
+	dst = append(dst, kind)
+	dst = binary.LittleEndian.AppendUint64(dst, id)
+	dst = append(dst, payload...)
+	binary.LittleEndian.PutUint32(dst[start+4:], crc32.ChecksumIEEE(dst[start+recordHeaderSize:]))
+	return dst
+}
+
+// nextRecord decodes the record at the start of data and returns it with
+// its encoded size. Payloads alias data.
+func nextRecord(data []byte) (record, int, error) {
+	if len(data) < recordHeaderSize {
+		return record{}, 0, errTornRecord
+	}
+	n := int(binary.LittleEndian.Uint32(data))
+	if n < recordFixedSize || len(data)-recordHeaderSize < n {
+		return record{}, 0, errTornRecord
+	}
+	body := data[recordHeaderSize : recordHeaderSize+n]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[4:]) {
+		return record{}, 0, errTornRecord
+	}
+	return record{
+		kind:    body[0],
+		id:      binary.LittleEndian.Uint64(body[1:]),
+		payload: body[recordFixedSize:],
+	}, recordHeaderSize + n, nil
+}
@@ -0,0 +1,270 @@
+package typed
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrRingBufferFull is returned when a write would take a RingBuffer past
+// its MaxSize.
+var ErrRingBufferFull = errors.New("typed: ring buffer is full")
+
+// ringBufferMinRead is the smallest free space ReadFrom reads into.
+const ringBufferMinRead = 512
+
+type RingBufferOptions struct {
+	GrowthOptions     // Shared growth options
+	MaxSize       int // Most bytes the buffer may hold, 0 for no limit
+}
+
+type RingBufferOption func(*RingBufferOptions)
+
+func defaultRingBufferOptions() RingBufferOptions {
+	growth := DefaultGrowthOptions()
+	growth.InitialCapacity = 4096
+	return RingBufferOptions{
+		GrowthOptions: growth,
+	}
+}
+
+func WithRingBufferGrowthOptions(growthOpts ...GrowthOption) RingBufferOption {
+	return func(ro *RingBufferOptions) {
+		for _, opt := range growthOpts {
+			opt(&ro.GrowthOptions)
+		}
+	}
+}
+
+func WithRingBufferMaxSize(n int) RingBufferOption {
+	if n <= 0 {
+		panic("Ring buffer max size must be greater than 0")
+	}
+	return func(ro *RingBufferOptions) {
+		ro.MaxSize = n
+	}
+}
+
+// RingBuffer is a growable circular byte buffer. It implements io.Reader,
+// io.Writer, io.ByteReader, io.ByteWriter, io.WriterTo and io.ReaderFrom,
+// and copies bytes in at most two chunks per call.
+type RingBuffer struct {
+	buf  []byte
+	r    int // index of the first unread byte
+	n    int // number of unread bytes
+	opts RingBufferOptions
+}
+
+func NewRingBuffer(opts ...RingBufferOption) *RingBuffer {
+	o := defaultRingBufferOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.MaxSize > 0 {
+		o.InitialCapacity = min(o.InitialCapacity, o.MaxSize)
+	}
+	return &RingBuffer{
+		buf:  make([]byte, o.InitialCapacity),
+		opts: o,
+	}
+}
+
+// Len returns the number of unread bytes.
+func (b *RingBuffer) Len() int {
+	return b.n
+}
+
+func (b *RingBuffer) Cap() int {
+	return len(b.buf)
+}
+
+// Slices returns the unread bytes as two slices, a followed by b, that
+// alias the buffer. b is empty unless the data wraps around. They are
+// valid until the next write, and suit vectored I/O such as net.Buffers.
+func (b *RingBuffer) Slices() (first, second []byte) {
+	if b.n == 0 {
+		return nil, nil
+	}
+	end := b.r + b.n
+	if end <= len(b.buf) {
+		return b.buf[b.r:end], nil
+	}
+	return b.buf[b.r:], b.buf[:end-len(b.buf)]
+}
+
+// free returns the writable space as two slices.
+func (b *RingBuffer) free() (first, second []byte) {
+	w := (b.r + b.n) % max(len(b.buf), 1)
+	if b.n == len(b.buf) {
+		return nil, nil
+	}
+	if w >= b.r {
+		return b.buf[w:], b.buf[:b.r]
+	}
+	return b.buf[w:b.r], nil
+}
+
+// reserve makes room for n more bytes, up to MaxSize, and returns how many
+// fit.
+func (b *RingBuffer) reserve(n int) int {
+	need := b.n + n
+	if b.opts.MaxSize > 0 && need > b.opts.MaxSize {
+		need = b.opts.MaxSize
+	}
+	if need > len(b.buf) {
+		newCap := b.opts.nextCap(len(b.buf), need)
+		if b.opts.MaxSize > 0 {
+			newCap = min(newCap, b.opts.MaxSize)
+		}
+		b.realloc(newCap)
+	}
+	return min(n, len(b.buf)-b.n)
+}
+
+// realloc moves the unread bytes to the start of a new buffer.
+func (b *RingBuffer) realloc(newCap int) {
+	newBuf := make([]byte, newCap)
+	first, second := b.Slices()
+	copy(newBuf[copy(newBuf, first):], second)
+	b.buf = newBuf
+	b.r = 0
+}
+
+// Write appends p to the buffer. It only fails with ErrRingBufferFull,
+// after writing as much of p as fits.
+func (b *RingBuffer) Write(p []byte) (int, error) {
+	n := b.reserve(len(p))
+	first, second := b.free()
+	copy(second, p[copy(first, p[:n]):n])
+	b.n += n
+	if n < len(p) {
+		return n, ErrRingBufferFull
+	}
+	return n, nil
+}
+
+func (b *RingBuffer) WriteByte(c byte) error {
+	if b.reserve(1) == 0 {
+		return ErrRingBufferFull
+	}
+	b.buf[(b.r+b.n)%len(b.buf)] = c
+	b.n++
+	return nil
+}
+
+// Read moves up to len(p) bytes into p. It returns io.EOF when the buffer
+// is empty and p is not.
+func (b *RingBuffer) Read(p []byte) (int, error) {
+	if b.n == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	first, second := b.Slices()
+	n := copy(p, first)
+	n += copy(p[n:], second)
+	b.consume(n)
+	return n, nil
+}
+
+func (b *RingBuffer) ReadByte() (byte, error) {
+	if b.n == 0 {
+		return 0, io.EOF
+	}
+	c := b.buf[b.r]
+	b.consume(1)
+	return c, nil
+}
+
+// Peek returns the next n bytes without consuming them. The slice aliases
+// the buffer and is valid until the next write. If fewer than n bytes are
+// buffered, Peek returns them all with io.EOF.
+func (b *RingBuffer) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		panic("Peek count must not be negative")
+	}
+	var err error
+	if n > b.n {
+		n, err = b.n, io.EOF
+	}
+	if b.r+n > len(b.buf) {
+		b.realloc(len(b.buf)) // unwrap so the bytes are contiguous
+	}
+	return b.buf[b.r : b.r+n], err
+}
+
+// Discard skips the next n bytes. If fewer than n bytes are buffered, it
+// discards them all and returns io.EOF.
+func (b *RingBuffer) Discard(n int) (int, error) {
+	if n < 0 {
+		panic("Discard count must not be negative")
+	}
+	if n > b.n {
+		d := b.n
+		b.consume(d)
+		return d, io.EOF
+	}
+	b.consume(n)
+	return n, nil
+}
+
+// WriteTo writes the buffered bytes to w until the buffer is empty or w
+// fails.
+func (b *RingBuffer) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for b.n > 0 {
+		first, _ := b.Slices()
+		n, err := w.Write(first)
+		if n > len(first) {
+			panic("typed: RingBuffer.WriteTo: invalid Write count")
+		}
+		b.consume(n)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		if n < len(first) {
+			return total, io.ErrShortWrite
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom reads from r until io.EOF, growing the buffer as needed. It
+// stops with ErrRingBufferFull once MaxSize is reached.
+func (b *RingBuffer) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for {
+		if b.reserve(ringBufferMinRead) == 0 {
+			return total, ErrRingBufferFull
+		}
+		first, _ := b.free()
+		n, err := r.Read(first)
+		if n < 0 {
+			panic("typed: RingBuffer.ReadFrom: negative Read count")
+		}
+		b.n += n
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// Reset empties the buffer but keeps its storage.
+func (b *RingBuffer) Reset() {
+	b.r = 0
+	b.n = 0
+}
+
+func (b *RingBuffer) consume(n int) {
+	b.n -= n
+	if b.n == 0 {
+		b.r = 0 // keep the next write contiguous
+		return
+	}
+	b.r = (b.r + n) % len(b.buf)
+}
@@ -0,0 +1,244 @@
+package typed
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRingBuffer(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		opts  []RingBufferOption
+		steps []step
+	}{
+		{
+			name: "write and read",
+			steps: []step{
+				{"read", 4, io.EOF},
+				{"write", "hello", nil},
+				{"len", nil, 5},
+				{"read", 3, "hel"},
+				{"readByte", nil, byte('l')},
+				{"read", 10, "o"},
+				{"readByte", nil, io.EOF},
+			},
+		},
+		{
+			name: "wraps around",
+			opts: []RingBufferOption{WithRingBufferGrowthOptions(WithInitialCapacity(8))},
+			steps: []step{
+				{"write", "abcdef", nil},
+				{"read", 4, "abcd"},
+				{"write", "ghijk", nil},
+				{"cap", nil, 8},
+				{"slices", nil, [2]string{"efgh", "ijk"}},
+				{"peek", 5, "efghi"},
+				{"slices", nil, [2]string{"efghijk", ""}},
+				{"discard", 2, 2},
+				{"read", 10, "ghijk"},
+			},
+		},
+		{
+			name: "grows keeping order",
+			opts: []RingBufferOption{WithRingBufferGrowthOptions(WithInitialCapacity(4))},
+			steps: []step{
+				{"write", "abc", nil},
+				{"read", 2, "ab"},
+				{"write", "defghij", nil},
+				{"cap", nil, 8},
+				{"read", 20, "cdefghij"},
+			},
+		},
+		{
+			name: "max size",
+			opts: []RingBufferOption{WithRingBufferMaxSize(6)},
+			steps: []step{
+				{"write", "abcd", nil},
+				{"write", "efgh", ErrRingBufferFull},
+				{"len", nil, 6},
+				{"writeByte", byte('x'), ErrRingBufferFull},
+				{"read", 2, "ab"},
+				{"writeByte", byte('x'), nil},
+				{"read", 10, "cdefx"},
+			},
+		},
+		{
+			name: "peek and discard past the end",
+			steps: []step{
+				{"write", "abc", nil},
+				{"peek", 5, io.EOF},
+				{"discard", 5, io.EOF},
+				{"len", nil, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewRingBuffer(tt.opts...)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "write":
+					_, err := b.Write([]byte(step.value.(string)))
+					if expected, _ := step.expected.(error); err != expected {
+						t.Errorf("step %d: write expected error %v, got %v", i, expected, err)
+					}
+				case "writeByte":
+					err := b.WriteByte(step.value.(byte))
+					if expected, _ := step.expected.(error); err != expected {
+						t.Errorf("step %d: writeByte expected error %v, got %v", i, expected, err)
+					}
+				case "read":
+					p := make([]byte, step.value.(int))
+					n, err := b.Read(p)
+					if expected, ok := step.expected.(error); ok {
+						if err != expected {
+							t.Errorf("step %d: read expected error %v, got %v", i, expected, err)
+						}
+					} else if err != nil || string(p[:n]) != step.expected.(string) {
+						t.Errorf("step %d: read expected %q, got %q (err=%v)", i, step.expected, p[:n], err)
+					}
+				case "readByte":
+					c, err := b.ReadByte()
+					if expected, ok := step.expected.(error); ok {
+						if err != expected {
+							t.Errorf("step %d: readByte expected error %v, got %v", i, expected, err)
+						}
+					} else if err != nil || c != step.expected.(byte) {
+						t.Errorf("step %d: readByte expected %q, got %q (err=%v)", i, step.expected, c, err)
+					}
+				case "peek":
+					p, err := b.Peek(step.value.(int))
+					if expected, ok := step.expected.(error); ok {
+						if err != expected {
+							t.Errorf("step %d: peek expected error %v, got %v", i, expected, err)
+						}
+					} else if err != nil || string(p) != step.expected.(string) {
+						t.Errorf("step %d: peek expected %q, got %q (err=%v)", i, step.expected, p, err)
+					}
+				case "discard":
+					n, err := b.Discard(step.value.(int))
+					if expected, ok := step.expected.(error); ok {
+						if err != expected {
+							t.Errorf("step %d: discard expected error %v, got %v", i, expected, err)
+						}
+					} else if err != nil || n != step.expected.(int) {
+						t.Errorf("step %d: discard expected %v, got %v (err=%v)", i, step.expected, n, err)
+					}
+				case "slices":
+					first, second := b.Slices()
+					want := step.expected.([2]string)
+					if string(first) != want[0] || string(second) != want[1] {
+						t.Errorf("step %d: slices expected %q, got %q %q", i, want, first, second)
+					}
+				case "len":
+					if got := b.Len(); got != step.expected.(int) {
+						t.Errorf("step %d: len expected %v, got %v", i, step.expected, got)
+					}
+				case "cap":
+					if got := b.Cap(); got != step.expected.(int) {
+						t.Errorf("step %d: cap expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestRingBuffer_ReaderWriter(t *testing.T) {
+	data := strings.Repeat("0123456789abcdef", 1000)
+
+	b := NewRingBuffer(WithRingBufferGrowthOptions(WithInitialCapacity(64)))
+	n, err := b.ReadFrom(iotest.OneByteReader(strings.NewReader(data[:5000])))
+	if err != nil || n != 5000 {
+		t.Fatalf("readFrom expected 5000, got %d (err=%v)", n, err)
+	}
+	b.Discard(1000)
+	if _, err := b.ReadFrom(strings.NewReader(data[5000:])); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if _, err := b.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != data[1000:] {
+		t.Errorf("round trip lost data: got %d bytes", out.Len())
+	}
+
+	// The buffer passes the standard reader checks
+	b.Reset()
+	b.Write([]byte(data))
+	if err := iotest.TestReader(b, []byte(data)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRingBuffer_ReadFromFull(t *testing.T) {
+	b := NewRingBuffer(WithRingBufferMaxSize(100))
+	n, err := b.ReadFrom(strings.NewReader(strings.Repeat("x", 500)))
+	if n != 100 || !errors.Is(err, ErrRingBufferFull) {
+		t.Errorf("expected 100 bytes and ErrRingBufferFull, got %d (err=%v)", n, err)
+	}
+}
+
+// shortWriter accepts at most max bytes per call without reporting an
+// error.
+type shortWriter struct {
+	max int
+	bytes.Buffer
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	return w.Buffer.Write(p[:min(len(p), w.max)])
+}
+
+func TestRingBuffer_WriteToShort(t *testing.T) {
+	b := NewRingBuffer()
+	b.Write([]byte("hello"))
+	w := &shortWriter{max: 2}
+	n, err := b.WriteTo(w)
+	if n != 2 || err != io.ErrShortWrite {
+		t.Errorf("expected 2 bytes and io.ErrShortWrite, got %d (err=%v)", n, err)
+	}
+	if b.Len() != 3 || w.String() != "he" {
+		t.Errorf("expected 3 bytes left and \"he\" written, got %d and %q", b.Len(), w.String())
+	}
+
+	_, err = b.WriteTo(iotest.TruncateWriter(&w.Buffer, 0))
+	if err != nil || b.Len() != 0 {
+		t.Errorf("expected truncating writer to drain the buffer, got %d left (err=%v)", b.Len(), err)
+	}
+}
+
+// Example of using RingBuffer
+func ExampleRingBuffer() {
+	b := NewRingBuffer()
+
+	// Buffer incoming bytes
+	b.Write([]byte("GET / HTTP/1.1\r\n"))
+
+	// Look ahead without consuming
+	method, _ := b.Peek(3) // "GET"
+
+	// Send what is buffered with one vectored write
+	first, second := b.Slices()
+	bufs := net.Buffers{first, second}
+
+	// Prevent unused variable warnings in example
+	_, _ = method, bufs
+}
@@ -0,0 +1,400 @@
+package typed
+
+import (
+	"slices"
+	"sort"
+)
+
+// maxFreeSegments is how many empty blocks a segment list keeps around for
+// reuse before handing them back to the garbage collector.
+const maxFreeSegments = 2
+
+type segment[T any] struct {
+	items      []T
+	prev, next *segment[T]
+}
+
+// segments is a double-ended sequence stored as a linked list of
+// fixed-size blocks. Growing only allocates one block and shrinking only
+// releases empty blocks, so no operation ever copies the stored items.
+type segments[T any] struct {
+	head, tail *segment[T]
+	headIdx    int // index of the first item in head
+	tailIdx    int // index one past the last item in tail
+	size       int
+	blockSize  int
+	blocks     int // live blocks
+	free       []*segment[T]
+	stats      *Stats
+	hooks      ResizeHooks
+}
+
+func newSegments[T any](blockSize int, stats *Stats, hooks ResizeHooks) *segments[T] {
+	return &segments[T]{blockSize: blockSize, stats: stats, hooks: hooks}
+}
+
+func (s *segments[T]) newBlock() *segment[T] {
+	return &segment[T]{items: make([]T, s.blockSize)}
+}
+
+// grew reports a block that was just counted in the capacity.
+func (s *segments[T]) grew() {
+	c := s.cap()
+	recordResize[T](s.stats, s.hooks, c-s.blockSize, c, 0)
+}
+
+func (s *segments[T]) alloc() *segment[T] {
+	if n := len(s.free); n > 0 {
+		b := s.free[n-1]
+		s.free[n-1] = nil
+		s.free = s.free[:n-1]
+		s.blocks++
+		return b
+	}
+	b := s.newBlock()
+	s.blocks++
+	s.grew()
+	return b
+}
+
+func (s *segments[T]) release(b *segment[T]) {
+	b.prev, b.next = nil, nil
+	if len(s.free) < maxFreeSegments {
+		s.blocks--
+		s.free = append(s.free, b)
+		return
+	}
+	c := s.cap()
+	s.blocks--
+	recordResize[T](s.stats, s.hooks, c, c-s.blockSize, 0)
+}
+
+// init places the first block so that both ends have room to grow.
+func (s *segments[T]) init() {
+	s.head = s.alloc()
+	s.tail = s.head
+	s.headIdx = s.blockSize / 2
+	s.tailIdx = s.headIdx
+}
+
+func (s *segments[T]) pushBack(val T) {
+	if s.tail == nil {
+		s.init()
+	}
+	if s.tailIdx == s.blockSize {
+		if s.size == 0 {
+			// Reuse the empty block rather than leaving it behind as head
+			s.headIdx, s.tailIdx = 0, 0
+		} else {
+			b := s.alloc()
+			b.prev = s.tail
+			s.tail.next = b
+			s.tail = b
+			s.tailIdx = 0
+		}
+	}
+	s.tail.items[s.tailIdx] = val
+	s.tailIdx++
+	s.size++
+}
+
+func (s *segments[T]) pushFront(val T) {
+	if s.head == nil {
+		s.init()
+	}
+	if s.headIdx == 0 {
+		if s.size == 0 {
+			// Reuse the empty block rather than leaving it behind as tail,
+			// which happens with a block size of 1
+			s.headIdx, s.tailIdx = s.blockSize, s.blockSize
+		} else {
+			b := s.alloc()
+			b.next = s.head
+			s.head.prev = b
+			s.head = b
+			s.headIdx = s.blockSize
+		}
+	}
+	s.headIdx--
+	s.head.items[s.headIdx] = val
+	s.size++
+}
+
+func (s *segments[T]) popFront() (T, bool) {
+	var zero T
+	if s.size == 0 {
+		return zero, false
+	}
+	val := s.head.items[s.headIdx]
+	s.head.items[s.headIdx] = zero
+	s.headIdx++
+	s.size--
+	if s.size == 0 {
+		s.recenter()
+	} else if s.headIdx == s.blockSize {
+		old := s.head
+		s.head = old.next
+		s.head.prev = nil
+		s.headIdx = 0
+		s.release(old)
+	}
+	return val, true
+}
+
+func (s *segments[T]) popBack() (T, bool) {
+	var zero T
+	if s.size == 0 {
+		return zero, false
+	}
+	s.tailIdx--
+	val := s.tail.items[s.tailIdx]
+	s.tail.items[s.tailIdx] = zero
+	s.size--
+	if s.size == 0 {
+		s.recenter()
+	} else if s.tailIdx == 0 {
+		old := s.tail
+		s.tail = old.prev
+		s.tail.next = nil
+		s.tailIdx = s.blockSize
+		s.release(old)
+	}
+	return val, true
+}
+
+// recenter moves the indices of an empty list back to the middle of its
+// only block.
+func (s *segments[T]) recenter() {
+	s.headIdx = s.blockSize / 2
+	s.tailIdx = s.headIdx
+}
+
+func (s *segments[T]) front() (T, bool) {
+	var zero T
+	if s.size == 0 {
+		return zero, false
+	}
+	return s.head.items[s.headIdx], true
+}
+
+func (s *segments[T]) back() (T, bool) {
+	var zero T
+	if s.size == 0 {
+		return zero, false
+	}
+	return s.tail.items[s.tailIdx-1], true
+}
+
+// cap reports the number of slots held by live and free blocks.
+func (s *segments[T]) cap() int {
+	return (s.blocks + len(s.free)) * s.blockSize
+}
+
+// reserve allocates enough free blocks for pushBack to reach n items
+// without allocating.
+func (s *segments[T]) reserve(n int) {
+	for s.size+s.backRoom() < n {
+		s.free = append(s.free, s.newBlock())
+		s.grew()
+	}
+}
+
+// backRoom is how many pushBack calls fit in the blocks already held.
+func (s *segments[T]) backRoom() int {
+	room := len(s.free) * s.blockSize
+	if s.tail != nil {
+		return room + s.blockSize - s.tailIdx
+	}
+	if room > 0 {
+		room -= s.blockSize / 2 // init starts in the middle of the first block
+	}
+	return room
+}
+
+func (s *segments[T]) reset() {
+	for b := s.head; b != nil; {
+		next := b.next
+		clear(b.items)
+		s.release(b)
+		b = next
+	}
+	s.head, s.tail = nil, nil
+	s.headIdx, s.tailIdx = 0, 0
+	s.size = 0
+}
+
+func (s *segments[T]) appendTo(dst []T) []T {
+	for b := s.head; b != nil; b = b.next {
+		lo, hi := 0, s.blockSize
+		if b == s.head {
+			lo = s.headIdx
+		}
+		if b == s.tail {
+			hi = s.tailIdx
+		}
+		dst = append(dst, b.items[lo:hi]...)
+	}
+	return dst
+}
+
+// segCursor points at one slot of a segment list.
+type segCursor[T any] struct {
+	b   *segment[T]
+	idx int
+}
+
+func (c *segCursor[T]) ptr() *T {
+	return &c.b.items[c.idx]
+}
+
+// next and prev may step one slot past either end of the list, as long as
+// ptr is not called there.
+func (c *segCursor[T]) next() {
+	c.idx++
+	if c.idx == len(c.b.items) && c.b.next != nil {
+		c.b, c.idx = c.b.next, 0
+	}
+}
+
+func (c *segCursor[T]) prev() {
+	if c.idx == 0 && c.b.prev != nil {
+		c.b, c.idx = c.b.prev, len(c.b.items)
+	}
+	c.idx--
+}
+
+// cursor returns a cursor at the i-th item, walking the blocks from
+// whichever end is closer.
+func (s *segments[T]) cursor(i int) segCursor[T] {
+	if i > s.size/2 {
+		p := s.tailIdx - (s.size - i)
+		b := s.tail
+		for p < 0 {
+			b = b.prev
+			p += s.blockSize
+		}
+		return segCursor[T]{b: b, idx: p}
+	}
+	p := s.headIdx + i
+	b := s.head
+	for p >= s.blockSize {
+		b = b.next
+		p -= s.blockSize
+	}
+	return segCursor[T]{b: b, idx: p}
+}
+
+// search returns the first index at which pred is true, assuming pred is
+// false and then true from front to back. It skips whole blocks by their
+// last item and binary searches only the block where pred turns true.
+func (s *segments[T]) search(pred func(T) bool) int {
+	if s.size == 0 {
+		return 0
+	}
+	base, lo := 0, s.headIdx
+	for b := s.head; ; b = b.next {
+		hi := s.blockSize
+		if b == s.tail {
+			hi = s.tailIdx
+		}
+		if b == s.tail || pred(b.items[hi-1]) {
+			return base + sort.Search(hi-lo, func(k int) bool { return pred(b.items[lo+k]) })
+		}
+		base += hi - lo
+		lo = 0
+	}
+}
+
+// insert places val at index i, moving the items on the shorter side of
+// i by one.
+func (s *segments[T]) insert(i int, val T) {
+	if i < s.size/2 {
+		s.pushFront(val)
+		c := s.cursor(0)
+		for k := 0; k < i; k++ {
+			p := c.ptr()
+			c.next()
+			*p, *c.ptr() = *c.ptr(), *p
+		}
+		return
+	}
+	s.pushBack(val)
+	c := s.cursor(s.size - 1)
+	for k := s.size - 1; k > i; k-- {
+		p := c.ptr()
+		c.prev()
+		*p, *c.ptr() = *c.ptr(), *p
+	}
+}
+
+// removeRange deletes the items in [i, j), moving the items on the
+// shorter side into the gap.
+func (s *segments[T]) removeRange(i, j int) {
+	m := j - i
+	if i < s.size-j {
+		if i > 0 {
+			dst, src := s.cursor(j-1), s.cursor(i-1)
+			for k := 0; k < i; k++ {
+				*dst.ptr() = *src.ptr()
+				dst.prev()
+				src.prev()
+			}
+		}
+		for ; m > 0; m-- {
+			s.popFront()
+		}
+		return
+	}
+	if j < s.size {
+		dst, src := s.cursor(i), s.cursor(j)
+		for k := j; k < s.size; k++ {
+			*dst.ptr() = *src.ptr()
+			dst.next()
+			src.next()
+		}
+	}
+	for ; m > 0; m-- {
+		s.popBack()
+	}
+}
+
+// rotate moves n items from the front to the back, or size-n items from
+// the back to the front, whichever is fewer.
+func (s *segments[T]) rotate(n int) {
+	if n <= s.size-n {
+		for ; n > 0; n-- {
+			val, _ := s.popFront()
+			s.pushBack(val)
+		}
+		return
+	}
+	for n = s.size - n; n > 0; n-- {
+		val, _ := s.popBack()
+		s.pushFront(val)
+	}
+}
+
+func (s *segments[T]) reverse() {
+	if s.size < 2 {
+		return
+	}
+	lo, hi := s.cursor(0), s.cursor(s.size-1)
+	for i, j := 0, s.size-1; i < j; i, j = i+1, j-1 {
+		*lo.ptr(), *hi.ptr() = *hi.ptr(), *lo.ptr()
+		lo.next()
+		hi.prev()
+	}
+}
+
+func (s *segments[T]) sortFunc(cmp func(a, b T) int) {
+	if s.size < 2 {
+		return
+	}
+	items := s.appendTo(make([]T, 0, s.size))
+	slices.SortFunc(items, cmp)
+	c := s.cursor(0)
+	for _, v := range items {
+		*c.ptr() = v
+		c.next()
+	}
+}
@@ -1,7 +1,8 @@
 package typed
 
 type Set[T comparable] struct {
-	data map[T]struct{}
+	data  map[T]struct{}
+	stats Stats
 }
 
 func NewSet[T comparable]() *Set[T] {
@@ -9,11 +10,19 @@ func NewSet[T comparable]() *Set[T] {
 }
 
 func (s *Set[T]) Add(val T) {
+	n := len(s.data)
 	s.data[val] = struct{}{}
+	if len(s.data) > n {
+		s.stats.recordPush(len(s.data))
+	}
 }
 
 func (s *Set[T]) Remove(val T) {
+	n := len(s.data)
 	delete(s.data, val)
+	if len(s.data) < n {
+		s.stats.recordPop()
+	}
 }
 
 func (s *Set[T]) Contains(val T) bool {
@@ -25,6 +34,13 @@ func (s *Set[T]) Size() int {
 	return len(s.data)
 }
 
+// Stats returns usage counters for the set. Pushes and Pops count values
+// that were actually added or removed. Maps resize internally, so the
+// resize counters stay at zero.
+func (s *Set[T]) Stats() Stats {
+	return s.stats
+}
+
 func (s *Set[T]) Clear() {
 	s.data = make(map[T]struct{})
 }
@@ -0,0 +1,164 @@
+package typed
+
+import "time"
+
+type SlidingWindowOptions struct {
+	Count int           // Most recent items kept, 0 for no count limit
+	Span  time.Duration // Age after which items leave the window, 0 for no age limit
+	Clock func() time.Time
+}
+
+type SlidingWindowOption func(*SlidingWindowOptions)
+
+func WithWindowCount(n int) SlidingWindowOption {
+	if n <= 0 {
+		panic("Window count must be greater than 0")
+	}
+	return func(so *SlidingWindowOptions) {
+		so.Count = n
+	}
+}
+
+func WithWindowSpan(d time.Duration) SlidingWindowOption {
+	if d <= 0 {
+		panic("Window span must be greater than 0")
+	}
+	return func(so *SlidingWindowOptions) {
+		so.Span = d
+	}
+}
+
+// WithWindowClock replaces time.Now as the source of the current time.
+func WithWindowClock(now func() time.Time) SlidingWindowOption {
+	if now == nil {
+		panic("Window clock must not be nil")
+	}
+	return func(so *SlidingWindowOptions) {
+		so.Clock = now
+	}
+}
+
+type windowEntry[T any] struct {
+	val T
+	seq uint64
+}
+
+// SlidingWindow tracks the minimum and maximum of the most recent items,
+// limited by count, age or both. Push, Min and Max run in amortized O(1)
+// using two monotonic deques.
+type SlidingWindow[T any] struct {
+	less  Comparator[T]
+	opts  SlidingWindowOptions
+	mins  *Deque[windowEntry[T]] // increasing from front to back
+	maxs  *Deque[windowEntry[T]] // decreasing from front to back
+	times *Deque[time.Time]      // push time of every item in the window, with Span only
+	first uint64                 // sequence number of the oldest item in the window
+	next  uint64                 // sequence number of the next push
+}
+
+// NewSlidingWindow creates a window ordered by less, which reports whether
+// a is smaller than b. At least one of WithWindowCount and WithWindowSpan
+// is required.
+func NewSlidingWindow[T any](less Comparator[T], opts ...SlidingWindowOption) *SlidingWindow[T] {
+	o := SlidingWindowOptions{Clock: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Count == 0 && o.Span == 0 {
+		panic("SlidingWindow needs a window count or span")
+	}
+	w := &SlidingWindow[T]{
+		less: less,
+		opts: o,
+		mins: NewDeque[windowEntry[T]](),
+		maxs: NewDeque[windowEntry[T]](),
+	}
+	if o.Span > 0 {
+		w.times = NewDeque[time.Time]()
+	}
+	return w
+}
+
+func (w *SlidingWindow[T]) Push(val T) {
+	now := w.opts.Clock()
+	e := windowEntry[T]{val: val, seq: w.next}
+	w.next++
+	if w.times != nil {
+		w.times.PushBack(now)
+	}
+
+	for {
+		back, ok := w.mins.PeekBack()
+		if !ok || w.less(back.val, val) {
+			break
+		}
+		w.mins.PopBack()
+	}
+	w.mins.PushBack(e)
+	for {
+		back, ok := w.maxs.PeekBack()
+		if !ok || w.less(val, back.val) {
+			break
+		}
+		w.maxs.PopBack()
+	}
+	w.maxs.PushBack(e)
+
+	w.evict(now)
+}
+
+// Min returns the smallest item in the window.
+func (w *SlidingWindow[T]) Min() (T, bool) {
+	w.evict(w.opts.Clock())
+	e, ok := w.mins.PeekFront()
+	return e.val, ok
+}
+
+// Max returns the largest item in the window.
+func (w *SlidingWindow[T]) Max() (T, bool) {
+	w.evict(w.opts.Clock())
+	e, ok := w.maxs.PeekFront()
+	return e.val, ok
+}
+
+// Len returns the number of items in the window.
+func (w *SlidingWindow[T]) Len() int {
+	w.evict(w.opts.Clock())
+	return int(w.next - w.first)
+}
+
+// evict drops the items that have left the window at now.
+func (w *SlidingWindow[T]) evict(now time.Time) {
+	if w.opts.Count > 0 && w.next-w.first > uint64(w.opts.Count) {
+		n := w.next - uint64(w.opts.Count) - w.first
+		w.first += n
+		for ; w.times != nil && n > 0; n-- {
+			w.times.PopFront()
+		}
+	}
+	if w.times != nil {
+		cutoff := now.Add(-w.opts.Span)
+		for {
+			t, ok := w.times.PeekFront()
+			if !ok || t.After(cutoff) {
+				break
+			}
+			w.times.PopFront()
+			w.first++
+		}
+	}
+	for {
+		e, ok := w.mins.PeekFront()
+		if !ok || e.seq >= w.first {
+			break
+		}
+		w.mins.PopFront()
+	}
+	for {
+		e, ok := w.maxs.PeekFront()
+		if !ok || e.seq >= w.first {
+			break
+		}
+		w.maxs.PopFront()
+	}
+}
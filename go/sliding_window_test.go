@@ -0,0 +1,165 @@
+package typed
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		opts  []SlidingWindowOption
+		steps []step
+	}{
+		{
+			name: "count window",
+			opts: []SlidingWindowOption{WithWindowCount(3)},
+			steps: []step{
+				{"len", nil, 0},
+				{"max", nil, nil},
+				{"min", nil, nil},
+				{"push", 5, nil},
+				{"push", 1, nil},
+				{"push", 3, nil},
+				{"max", nil, 5},
+				{"min", nil, 1},
+				{"push", 2, nil}, // 5 leaves
+				{"max", nil, 3},
+				{"push", 2, nil}, // 1 leaves
+				{"min", nil, 2},
+				{"len", nil, 3},
+			},
+		},
+		{
+			name: "time window",
+			opts: []SlidingWindowOption{WithWindowSpan(10 * time.Second)},
+			steps: []step{
+				{"push", 9, nil},
+				{"advance", 4, nil},
+				{"push", 2, nil},
+				{"advance", 4, nil},
+				{"push", 4, nil},
+				{"max", nil, 9},
+				{"min", nil, 2},
+				{"advance", 2, nil}, // 9 is 10s old
+				{"max", nil, 4},
+				{"len", nil, 2},
+				{"advance", 4, nil},
+				{"min", nil, 4},
+				{"advance", 10, nil},
+				{"len", nil, 0},
+				{"max", nil, nil},
+			},
+		},
+		{
+			name: "count and time window",
+			opts: []SlidingWindowOption{WithWindowCount(2), WithWindowSpan(5 * time.Second)},
+			steps: []step{
+				{"push", 1, nil},
+				{"push", 7, nil},
+				{"push", 3, nil},
+				{"max", nil, 7},
+				{"min", nil, 3},
+				{"advance", 5, nil},
+				{"len", nil, 0},
+				{"push", 6, nil},
+				{"max", nil, 6},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			w := NewSlidingWindow[int](func(a, b int) bool { return a < b },
+				append(tt.opts, WithWindowClock(func() time.Time { return now }))...)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					w.Push(step.value.(int))
+				case "advance":
+					now = now.Add(time.Duration(step.value.(int)) * time.Second)
+				case "max", "min":
+					var val int
+					var ok bool
+					if step.op == "max" {
+						val, ok = w.Max()
+					} else {
+						val, ok = w.Min()
+					}
+					if step.expected == nil {
+						if ok {
+							t.Errorf("step %d: %s expected to fail, got %v", i, step.op, val)
+						}
+					} else if !ok || val != step.expected.(int) {
+						t.Errorf("step %d: %s expected %v, got %v (ok=%v)", i, step.op, step.expected, val, ok)
+					}
+				case "len":
+					if got := w.Len(); got != step.expected.(int) {
+						t.Errorf("step %d: len expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestSlidingWindow_BruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const size = 16
+	w := NewSlidingWindow[int](func(a, b int) bool { return a < b }, WithWindowCount(size))
+	var samples []int
+
+	for i := 0; i < 2000; i++ {
+		v := r.Intn(50)
+		w.Push(v)
+		samples = append(samples, v)
+		window := samples[max(0, len(samples)-size):]
+
+		lo, hi := window[0], window[0]
+		for _, s := range window {
+			lo, hi = min(lo, s), max(hi, s)
+		}
+		gotMin, _ := w.Min()
+		gotMax, _ := w.Max()
+		if gotMin != lo || gotMax != hi || w.Len() != len(window) {
+			t.Fatalf("push %d: expected min %d max %d len %d, got %d %d %d",
+				i, lo, hi, len(window), gotMin, gotMax, w.Len())
+		}
+	}
+}
+
+func TestSlidingWindow_RequiresLimit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic without a count or span")
+		}
+	}()
+	NewSlidingWindow[int](func(a, b int) bool { return a < b })
+}
+
+// Example of using SlidingWindow
+func ExampleSlidingWindow() {
+	// Max latency over the last minute
+	w := NewSlidingWindow[time.Duration](func(a, b time.Duration) bool { return a < b },
+		WithWindowSpan(time.Minute))
+
+	w.Push(120 * time.Millisecond)
+	w.Push(80 * time.Millisecond)
+
+	worst, ok := w.Max() // 120ms
+	best, _ := w.Min()   // 80ms
+
+	// Prevent unused variable warnings in example
+	_, _, _ = worst, ok, best
+}
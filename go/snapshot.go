@@ -0,0 +1,41 @@
+package typed
+
+// Snapshot is a read-only view of a Queue or Deque at the moment Snapshot
+// was called. It shares the container's backing array until the container
+// is next modified, so taking one is O(1) and reading it never blocks the
+// writer.
+type Snapshot[T any] struct {
+	data  []T
+	start int
+	size  int
+}
+
+func (s Snapshot[T]) Len() int {
+	return s.size
+}
+
+// At returns the i-th item from the front. It panics if i is out of range.
+func (s Snapshot[T]) At(i int) T {
+	if i < 0 || i >= s.size {
+		panic("Snapshot index out of range")
+	}
+	return s.data[(s.start+i)%len(s.data)]
+}
+
+// Range calls fn for each item from front to back until fn returns false.
+func (s Snapshot[T]) Range(fn func(i int, val T) bool) {
+	for i := 0; i < s.size; i++ {
+		if !fn(i, s.data[(s.start+i)%len(s.data)]) {
+			return
+		}
+	}
+}
+
+// Items returns a copy of the items from front to back.
+func (s Snapshot[T]) Items() []T {
+	cp := make([]T, s.size)
+	for i := range cp {
+		cp[i] = s.data[(s.start+i)%len(s.data)]
+	}
+	return cp
+}
@@ -0,0 +1,258 @@
+package typed
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type snapshotter interface {
+	Snapshot() Snapshot[int]
+	Size() int
+}
+
+func TestSnapshot(t *testing.T) {
+	type backend struct {
+		name     string
+		new      func() (snapshotter, func(int), func() (int, bool), func())
+		reversed bool // snapshots list the newest item first
+	}
+	backends := []backend{
+		{"queue", func() (snapshotter, func(int), func() (int, bool), func()) {
+			q := NewQueue[int](WithQueueGrowthOptions(WithInitialCapacity(8)))
+			return q, q.Push, q.Pop, q.Reset
+		}, false},
+		{"segmented queue", func() (snapshotter, func(int), func() (int, bool), func()) {
+			q := NewQueue[int](WithQueueSegmentSize(4))
+			return q, q.Push, q.Pop, q.Reset
+		}, false},
+		{"deque", func() (snapshotter, func(int), func() (int, bool), func()) {
+			d := NewDeque[int](WithDequeGrowthOptions(WithInitialCapacity(8)))
+			return d, d.PushBack, d.PopFront, d.Reset
+		}, false},
+		{"deque from front", func() (snapshotter, func(int), func() (int, bool), func()) {
+			d := NewDeque[int](WithDequeGrowthOptions(WithInitialCapacity(8)))
+			return d, d.PushFront, d.PopBack, d.Reset
+		}, true},
+		{"segmented deque", func() (snapshotter, func(int), func() (int, bool), func()) {
+			d := NewDeque[int](WithDequeSegmentSize(4))
+			return d, d.PushBack, d.PopFront, d.Reset
+		}, false},
+	}
+
+	type step struct {
+		op    string
+		value int
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"push after snapshot", []step{{"push", 5}}},
+		{"pop then push over the old slots", []step{{"pop", 3}, {"push", 8}}},
+		{"push until grow", []step{{"push", 20}}},
+		{"pop until shrink", []step{{"pop", 6}}},
+		{"reset", []step{{"reset", 0}, {"push", 3}}},
+	}
+
+	for _, b := range backends {
+		for _, tt := range tests {
+			t.Run(b.name+"/"+tt.name, func(t *testing.T) {
+				c, push, pop, reset := b.new()
+				// Wrap the ring so the snapshot does not start at index 0
+				for i := 0; i < 3; i++ {
+					push(-1)
+					pop()
+				}
+				model := NewQueue[int]()
+				for i := 0; i < 6; i++ {
+					push(i)
+					model.Push(i)
+				}
+				snap := c.Snapshot()
+				want := snap.Items()
+
+				next := 100
+				for i, step := range tt.steps {
+					for j := 0; j < step.value; j++ {
+						switch step.op {
+						case "push":
+							push(next)
+							model.Push(next)
+							next++
+						case "pop":
+							pop()
+							model.Pop()
+						}
+					}
+					if step.op == "reset" {
+						reset()
+						model.Reset()
+					}
+					if got := snap.Items(); !reflect.DeepEqual(got, want) {
+						t.Fatalf("step %d: snapshot changed from %v to %v", i, want, got)
+					}
+				}
+				first, last := 0, 5
+				if b.reversed {
+					first, last = 5, 0
+				}
+				if snap.Len() != 6 || snap.At(0) != first || snap.At(5) != last {
+					t.Errorf("unexpected snapshot %v", snap.Items())
+				}
+
+				// The container itself must be unaffected by the snapshot
+				got := c.Snapshot().Items()
+				expected := model.Snapshot().Items()
+				if b.reversed {
+					for i, j := 0, len(got)-1; i < j; i, j = i+1, j-1 {
+						got[i], got[j] = got[j], got[i]
+					}
+				}
+				if !reflect.DeepEqual(got, expected) {
+					t.Errorf("expected container %v, got %v", expected, got)
+				}
+			})
+		}
+	}
+}
+
+func TestSnapshot_MultipleLive(t *testing.T) {
+	// An empty snapshot must not release an older one still in use
+	q := NewQueue[int]()
+	d := NewDeque[int]()
+	q.Push(1)
+	q.Push(2)
+	d.PushBack(1)
+	d.PushBack(2)
+	qs, ds := q.Snapshot(), d.Snapshot()
+	q.Pop()
+	q.Pop()
+	d.PopFront()
+	d.PopFront()
+	qe, de := q.Snapshot(), d.Snapshot()
+	for i := 3; i <= 5; i++ {
+		q.Push(i)
+		d.PushBack(i)
+	}
+	for name, snap := range map[string]Snapshot[int]{"queue": qs, "deque": ds} {
+		if got := snap.Items(); !reflect.DeepEqual(got, []int{1, 2}) {
+			t.Errorf("%s: expected older snapshot [1 2], got %v", name, got)
+		}
+	}
+	if qe.Len() != 0 || de.Len() != 0 {
+		t.Errorf("expected empty snapshots to stay empty, got %v and %v", qe.Items(), de.Items())
+	}
+
+	// Several snapshots taken between changes all keep their own view
+	var snaps []Snapshot[int]
+	var wants [][]int
+	for i := 0; i < 20; i++ {
+		if i%3 == 0 {
+			q.Pop()
+		} else {
+			q.Push(100 + i)
+		}
+		snaps = append(snaps, q.Snapshot())
+		wants = append(wants, snaps[len(snaps)-1].Items())
+	}
+	for i, snap := range snaps {
+		if got := snap.Items(); !reflect.DeepEqual(got, wants[i]) {
+			t.Errorf("snapshot %d: changed from %v to %v", i, wants[i], got)
+		}
+	}
+}
+
+func TestSnapshot_Range(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 5; i++ {
+		q.Push(i)
+	}
+	var seen []int
+	q.Snapshot().Range(func(i int, val int) bool {
+		seen = append(seen, val)
+		return i < 2
+	})
+	if !reflect.DeepEqual(seen, []int{0, 1, 2}) {
+		t.Errorf("expected [0 1 2], got %v", seen)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for out of range index")
+		}
+	}()
+	q.Snapshot().At(5)
+}
+
+func TestSnapshot_NoCopy(t *testing.T) {
+	q := NewQueue[int]()
+	d := NewDeque[int]()
+	for i := 0; i < 1000; i++ {
+		q.Push(i)
+		d.PushBack(i)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = q.Snapshot()
+		_ = d.Snapshot()
+	})
+	if allocs != 0 {
+		t.Errorf("expected snapshots not to allocate, got %v", allocs)
+	}
+}
+
+func TestSnapshot_ConcurrentRead(t *testing.T) {
+	var mu sync.Mutex
+	q := NewQueue[int]()
+	for i := 0; i < 100; i++ {
+		q.Push(i)
+	}
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		mu.Lock()
+		snap := q.Snapshot()
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Read without the lock while the writer keeps going
+			sum := 0
+			snap.Range(func(_ int, val int) bool {
+				sum += val
+				return true
+			})
+			if first := snap.At(0); sum != snap.Len()*(2*first+snap.Len()-1)/2 {
+				t.Errorf("snapshot changed while reading: sum %d", sum)
+			}
+		}()
+
+		mu.Lock()
+		for i := 0; i < 50; i++ {
+			q.Pop()
+			q.Push(100 + r*50 + i)
+		}
+		mu.Unlock()
+	}
+	wg.Wait()
+}
+
+// Example of using Snapshot
+func ExampleSnapshot() {
+	q := NewQueue[string]()
+	q.Push("a")
+	q.Push("b")
+
+	// O(1): shares the queue's storage until the queue changes
+	snap := q.Snapshot()
+	q.Push("c") // the queue copies its items instead of touching snap
+
+	snap.Range(func(i int, val string) bool {
+		// "a", then "b"
+		return true
+	})
+
+	// Prevent unused variable warnings in example
+	_ = snap.Len()
+}
@@ -0,0 +1,312 @@
+package typed
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var ErrSpillQueueClosed = errors.New("typed: spill queue is closed")
+
+type SpillQueueOptions struct {
+	MaxItems int    // Items kept in memory before spilling
+	MaxBytes int64  // Encoded bytes kept in memory before spilling, 0 to disable
+	Dir      string // Parent directory for temp files, "" for os.TempDir
+}
+
+type SpillQueueOption func(*SpillQueueOptions)
+
+func defaultSpillQueueOptions() SpillQueueOptions {
+	return SpillQueueOptions{
+		MaxItems: 1 << 16,
+	}
+}
+
+func WithSpillMaxItems(n int) SpillQueueOption {
+	if n <= 0 {
+		panic("Spill max items must be greater than 0")
+	}
+	return func(o *SpillQueueOptions) {
+		o.MaxItems = n
+	}
+}
+
+// WithSpillMaxBytes limits memory by the encoded size of the items. Each
+// item is encoded once on Push to measure it, and the encoding is kept
+// for when the item is spilled.
+func WithSpillMaxBytes(n int64) SpillQueueOption {
+	if n <= 0 {
+		panic("Spill max bytes must be greater than 0")
+	}
+	return func(o *SpillQueueOptions) {
+		o.MaxBytes = n
+	}
+}
+
+func WithSpillDir(dir string) SpillQueueOption {
+	return func(o *SpillQueueOptions) {
+		o.Dir = dir
+	}
+}
+
+type spillEntry[T any] struct {
+	val  T
+	size int64
+	data []byte // encoded val, kept from Push while in the tail
+}
+
+type spillFile struct {
+	path  string
+	count int
+}
+
+// SpillQueue is a FIFO queue that keeps its oldest and newest items in
+// memory and moves everything in between to temporary files once the
+// memory budget is used up. Half of the budget is reserved for each end.
+// Items are read back in order as the in-memory head runs dry.
+//
+// Close removes the temporary files. A SpillQueue is not safe for
+// concurrent use.
+type SpillQueue[T any] struct {
+	codec Codec[T]
+	opts  SpillQueueOptions
+
+	head, tail           *Queue[spillEntry[T]]
+	headBytes, tailBytes int64
+	files                *Queue[spillFile]
+	spilled              int
+	dir                  string
+	nextFile             int
+	buf                  []byte
+	closed               bool
+}
+
+// NewSpillQueue creates an empty queue. A nil codec selects JSONCodec.
+func NewSpillQueue[T any](codec Codec[T], opts ...SpillQueueOption) *SpillQueue[T] {
+	o := defaultSpillQueueOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if codec == nil {
+		codec = JSONCodec[T]{}
+	}
+	return &SpillQueue[T]{
+		codec: codec,
+		opts:  o,
+		head:  NewQueue[spillEntry[T]](),
+		tail:  NewQueue[spillEntry[T]](),
+		files: NewQueue[spillFile](),
+	}
+}
+
+// full reports whether one end of the queue has used its half of the budget.
+func (q *SpillQueue[T]) full(size int, bytes int64) bool {
+	if size >= max(1, q.opts.MaxItems/2) {
+		return true
+	}
+	return q.opts.MaxBytes > 0 && bytes >= max(1, q.opts.MaxBytes/2)
+}
+
+// Push adds val at the back. If it returns an error, val was not added,
+// so the push can be retried.
+func (q *SpillQueue[T]) Push(val T) error {
+	if q.closed {
+		return ErrSpillQueueClosed
+	}
+	e := spillEntry[T]{val: val}
+	if q.opts.MaxBytes > 0 {
+		data, err := q.codec.Encode(val)
+		if err != nil {
+			return err
+		}
+		e.size = int64(len(data))
+		e.data = data
+	}
+
+	if q.files.IsEmpty() && q.tail.IsEmpty() && !q.full(q.head.Size(), q.headBytes) {
+		// The head is never spilled, so it does not need the encoding
+		e.data = nil
+		q.head.Push(e)
+		q.headBytes += e.size
+		return nil
+	}
+	q.tail.Push(e)
+	q.tailBytes += e.size
+	if q.full(q.tail.Size(), q.tailBytes) {
+		if err := q.spillTail(); err != nil {
+			q.dropNewest()
+			return err
+		}
+	}
+	return nil
+}
+
+// dropNewest removes the item just pushed onto the tail, rotating the rest
+// so their order is kept.
+func (q *SpillQueue[T]) dropNewest() {
+	for n := q.tail.Size(); n > 1; n-- {
+		e, _ := q.tail.Pop()
+		q.tail.Push(e)
+	}
+	e, _ := q.tail.Pop()
+	q.tailBytes -= e.size
+}
+
+// spillTail writes the in-memory tail to a new temp file.
+func (q *SpillQueue[T]) spillTail() error {
+	if q.dir == "" {
+		dir, err := os.MkdirTemp(q.opts.Dir, "typed-spill-")
+		if err != nil {
+			return err
+		}
+		q.dir = dir
+	}
+
+	// Rotate through the tail once so it is unchanged if encoding fails.
+	q.buf = q.buf[:0]
+	count := q.tail.Size()
+	var encErr error
+	for i := 0; i < count; i++ {
+		e, _ := q.tail.Pop()
+		q.tail.Push(e)
+		if encErr != nil {
+			continue
+		}
+		data := e.data
+		if data == nil {
+			var err error
+			if data, err = q.codec.Encode(e.val); err != nil {
+				encErr = err
+				continue
+			}
+		}
+		q.buf = appendRecord(q.buf, 0, uint64(i), data)
+	}
+	if encErr != nil {
+		return encErr
+	}
+
+	path := filepath.Join(q.dir, fmt.Sprintf("%020d.spill", q.nextFile))
+	if err := os.WriteFile(path, q.buf, 0o600); err != nil {
+		return err
+	}
+	q.nextFile++
+	q.files.Push(spillFile{path: path, count: count})
+	q.spilled += count
+	q.tail.Reset()
+	q.tailBytes = 0
+	return nil
+}
+
+// refill moves the next batch of items into the empty head.
+func (q *SpillQueue[T]) refill() error {
+	f, ok := q.files.Peek()
+	if !ok {
+		q.head, q.tail = q.tail, q.head
+		q.headBytes, q.tailBytes = q.tailBytes, 0
+		return nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	var bytes int64
+	for off := 0; off < len(data); {
+		rec, n, err := nextRecord(data[off:])
+		if err != nil {
+			q.head.Reset()
+			return fmt.Errorf("typed: spill file %s: %w", f.path, err)
+		}
+		off += n
+		val, err := q.codec.Decode(rec.payload)
+		if err != nil {
+			q.head.Reset()
+			return err
+		}
+		size := int64(0)
+		if q.opts.MaxBytes > 0 {
+			size = int64(len(rec.payload))
+		}
+		q.head.Push(spillEntry[T]{val: val, size: size})
+		bytes += size
+	}
+	if q.head.Size() != f.count {
+		q.head.Reset()
+		return fmt.Errorf("typed: spill file %s: expected %d items, found %d", f.path, f.count, q.head.Size())
+	}
+
+	q.files.Pop()
+	q.spilled -= f.count
+	q.headBytes = bytes
+	// The items are already in the head, so a file left behind is only
+	// litter, and Close removes it with the rest of the directory.
+	_ = os.Remove(f.path)
+	return nil
+}
+
+// Pop removes the oldest item, reading it back from disk if needed.
+func (q *SpillQueue[T]) Pop() (T, bool, error) {
+	var zero T
+	if q.closed {
+		return zero, false, ErrSpillQueueClosed
+	}
+	if q.head.IsEmpty() {
+		if err := q.refill(); err != nil {
+			return zero, false, err
+		}
+	}
+	e, ok := q.head.Pop()
+	if !ok {
+		return zero, false, nil
+	}
+	q.headBytes -= e.size
+	return e.val, true, nil
+}
+
+// Peek returns the oldest item without removing it, reading it back from
+// disk if needed.
+func (q *SpillQueue[T]) Peek() (T, bool, error) {
+	var zero T
+	if q.closed {
+		return zero, false, ErrSpillQueueClosed
+	}
+	if q.head.IsEmpty() {
+		if err := q.refill(); err != nil {
+			return zero, false, err
+		}
+	}
+	e, ok := q.head.Peek()
+	return e.val, ok, nil
+}
+
+func (q *SpillQueue[T]) Size() int {
+	return q.head.Size() + q.spilled + q.tail.Size()
+}
+
+func (q *SpillQueue[T]) IsEmpty() bool {
+	return q.Size() == 0
+}
+
+// Spilled returns the number of items currently stored on disk.
+func (q *SpillQueue[T]) Spilled() int {
+	return q.spilled
+}
+
+// Close drops all items and removes the temporary files.
+func (q *SpillQueue[T]) Close() error {
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	q.head.Reset()
+	q.tail.Reset()
+	q.files.Reset()
+	q.spilled = 0
+	q.headBytes, q.tailBytes = 0, 0
+	if q.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(q.dir)
+}
@@ -0,0 +1,342 @@
+package typed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpillQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		opts  []SpillQueueOption
+		steps []step
+	}{
+		{
+			name: "stays in memory under budget",
+			opts: []SpillQueueOption{WithSpillMaxItems(8)},
+			steps: []step{
+				{"isEmpty", nil, true},
+				{"pop", nil, false},
+				{"pushMany", 4, 0},
+				{"spilled", nil, 0},
+				{"peek", nil, 0},
+				{"popMany", 4, 0},
+				{"isEmpty", nil, true},
+			},
+		},
+		{
+			name: "spills the middle past the item budget",
+			opts: []SpillQueueOption{WithSpillMaxItems(4)},
+			steps: []step{
+				{"pushMany", 20, 0},
+				{"size", nil, 20},
+				{"spilledAtLeast", nil, 10},
+				{"popMany", 7, 0},
+				{"pushMany", 5, 20},
+				{"popMany", 18, 7},
+				{"spilled", nil, 0},
+				{"isEmpty", nil, true},
+			},
+		},
+		{
+			name: "spills past the byte budget",
+			opts: []SpillQueueOption{WithSpillMaxItems(1000), WithSpillMaxBytes(16)},
+			steps: []step{
+				{"pushMany", 30, 100},
+				{"spilledAtLeast", nil, 20},
+				{"popMany", 30, 100},
+				{"isEmpty", nil, true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewSpillQueue[int](nil, append(tt.opts, WithSpillDir(t.TempDir()))...)
+			defer q.Close()
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "pushMany":
+					count := step.value.(int)
+					start := step.expected.(int)
+					for j := 0; j < count; j++ {
+						if err := q.Push(start + j); err != nil {
+							t.Fatalf("step %d: push: %v", i, err)
+						}
+					}
+				case "pop":
+					val, ok, err := q.Pop()
+					if err != nil || ok {
+						t.Errorf("step %d: pop expected to fail, got %v (ok=%v, err=%v)", i, val, ok, err)
+					}
+				case "popMany":
+					count := step.value.(int)
+					start := step.expected.(int)
+					for j := 0; j < count; j++ {
+						val, ok, err := q.Pop()
+						if err != nil || !ok || val != start+j {
+							t.Fatalf("step %d: popMany[%d] expected %d, got %v (ok=%v, err=%v)", i, j, start+j, val, ok, err)
+						}
+					}
+				case "peek":
+					val, ok, err := q.Peek()
+					if err != nil || !ok || val != step.expected.(int) {
+						t.Errorf("step %d: peek expected %v, got %v (ok=%v, err=%v)", i, step.expected, val, ok, err)
+					}
+				case "size":
+					if got := q.Size(); got != step.expected.(int) {
+						t.Errorf("step %d: size expected %v, got %v", i, step.expected, got)
+					}
+				case "spilled":
+					if got := q.Spilled(); got != step.expected.(int) {
+						t.Errorf("step %d: spilled expected %v, got %v", i, step.expected, got)
+					}
+				case "spilledAtLeast":
+					if got := q.Spilled(); got < step.expected.(int) {
+						t.Errorf("step %d: spilled expected at least %v, got %v", i, step.expected, got)
+					}
+				case "isEmpty":
+					if got := q.IsEmpty(); got != step.expected.(bool) {
+						t.Errorf("step %d: isEmpty expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestSpillQueue_InterleavedOrder(t *testing.T) {
+	q := NewSpillQueue[int](GobCodec[int]{}, WithSpillMaxItems(6), WithSpillDir(t.TempDir()))
+	defer q.Close()
+
+	next, want := 0, 0
+	for round := 0; round < 300; round++ {
+		n := (round * 7919) % 17
+		if round%3 != 2 {
+			for i := 0; i < n; i++ {
+				if err := q.Push(next); err != nil {
+					t.Fatal(err)
+				}
+				next++
+			}
+			continue
+		}
+		for i := 0; i < n; i++ {
+			val, ok, err := q.Pop()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				break
+			}
+			if val != want {
+				t.Fatalf("round %d: expected %d, got %d", round, want, val)
+			}
+			want++
+		}
+	}
+	for {
+		val, ok, err := q.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		if val != want {
+			t.Fatalf("drain: expected %d, got %d", want, val)
+		}
+		want++
+	}
+	if want != next {
+		t.Errorf("expected %d items, got %d", next, want)
+	}
+}
+
+func TestSpillQueue_CloseRemovesFiles(t *testing.T) {
+	parent := t.TempDir()
+	q := NewSpillQueue[string](nil, WithSpillMaxItems(2), WithSpillDir(parent))
+	for _, s := range []string{"a", "b", "c", "d", "e", "f"} {
+		if err := q.Push(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, _ := os.ReadDir(parent)
+	if len(entries) != 1 || !strings.HasPrefix(entries[0].Name(), "typed-spill-") {
+		t.Fatalf("expected a spill directory, got %v", entries)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = os.ReadDir(parent)
+	if len(entries) != 0 {
+		t.Errorf("expected temp files to be removed, got %v", entries)
+	}
+	if err := q.Push("g"); err != ErrSpillQueueClosed {
+		t.Errorf("expected ErrSpillQueueClosed, got %v", err)
+	}
+}
+
+func TestSpillQueue_CorruptSpillFile(t *testing.T) {
+	parent := t.TempDir()
+	q := NewSpillQueue[int](nil, WithSpillMaxItems(2), WithSpillDir(parent))
+	defer q.Close()
+	for i := 0; i < 6; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(parent, "typed-spill-*", "*.spill"))
+	if len(files) == 0 {
+		t.Fatal("expected spill files")
+	}
+	if err := os.Truncate(files[0], 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := q.Pop(); err != nil {
+		t.Fatalf("expected in-memory head to pop, got %v", err)
+	}
+	if _, _, err := q.Pop(); err == nil {
+		t.Error("expected an error reading a damaged spill file")
+	}
+}
+
+func TestSpillQueue_FailedSpill(t *testing.T) {
+	parent := filepath.Join(t.TempDir(), "missing")
+	q := NewSpillQueue[int](nil, WithSpillMaxItems(4), WithSpillDir(parent))
+	defer q.Close()
+
+	// The tail fills up at 2 items, and spilling fails without a directory
+	for i := 0; i < 3; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Push(3); err == nil {
+		t.Fatal("expected push to fail without a spill directory")
+	}
+	if q.Size() != 3 {
+		t.Fatalf("expected the failed push to be rolled back, got size %d", q.Size())
+	}
+
+	// Retrying once the directory exists adds the item exactly once
+	if err := os.Mkdir(parent, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 3; i < 8; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for want := 0; want < 8; want++ {
+		if val, ok, err := q.Pop(); err != nil || !ok || val != want {
+			t.Fatalf("expected %d, got %v (ok=%v, err=%v)", want, val, ok, err)
+		}
+	}
+	if !q.IsEmpty() {
+		t.Errorf("expected queue to be empty, got %d items", q.Size())
+	}
+}
+
+// spyCodec counts encodings and runs onDecode before each decoding.
+type spyCodec struct {
+	JSONCodec[int]
+	encodes  int
+	onDecode func()
+}
+
+func (c *spyCodec) Encode(val int) ([]byte, error) {
+	c.encodes++
+	return c.JSONCodec.Encode(val)
+}
+
+func (c *spyCodec) Decode(data []byte) (int, error) {
+	if c.onDecode != nil {
+		c.onDecode()
+	}
+	return c.JSONCodec.Decode(data)
+}
+
+func TestSpillQueue_EncodesOnce(t *testing.T) {
+	codec := &spyCodec{}
+	q := NewSpillQueue[int](codec, WithSpillMaxItems(4), WithSpillMaxBytes(1<<10), WithSpillDir(t.TempDir()))
+	defer q.Close()
+
+	// The encoding measured on Push is the one written when spilling
+	for i := 0; i < 20; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.Spilled() == 0 {
+		t.Fatal("expected items to be spilled")
+	}
+	if codec.encodes != 20 {
+		t.Errorf("expected 20 encodings, got %d", codec.encodes)
+	}
+	for want := 0; want < 20; want++ {
+		if val, ok, err := q.Pop(); err != nil || !ok || val != want {
+			t.Fatalf("expected %d, got %v (ok=%v, err=%v)", want, val, ok, err)
+		}
+	}
+}
+
+func TestSpillQueue_RemoveFailure(t *testing.T) {
+	parent := t.TempDir()
+	codec := &spyCodec{}
+	q := NewSpillQueue[int](codec, WithSpillMaxItems(2), WithSpillDir(parent))
+	defer q.Close()
+	for i := 0; i < 6; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Delete each spill file while it is being read, so removing it fails.
+	// Glob sorts the names, and the oldest file is the one being read.
+	codec.onDecode = func() {
+		files, _ := filepath.Glob(filepath.Join(parent, "typed-spill-*", "*.spill"))
+		if len(files) > 0 {
+			os.Remove(files[0])
+		}
+	}
+	if _, _, err := q.Pop(); err != nil {
+		t.Fatalf("expected in-memory head to pop, got %v", err)
+	}
+	if val, ok, err := q.Pop(); err != nil || !ok || val != 1 {
+		t.Fatalf("expected 1 despite the failed removal, got %v (ok=%v, err=%v)", val, ok, err)
+	}
+	for want := 2; want < 6; want++ {
+		if val, ok, err := q.Pop(); err != nil || !ok || val != want {
+			t.Fatalf("expected %d, got %v (ok=%v, err=%v)", want, val, ok, err)
+		}
+	}
+}
+
+// Example of using SpillQueue
+func ExampleSpillQueue() {
+	// Keep at most 1024 items in memory, spilling the rest to temp files
+	q := NewSpillQueue[string](JSONCodec[string]{}, WithSpillMaxItems(1024))
+	defer q.Close()
+
+	// Push and pop as with Queue, but I/O errors are reported
+	_ = q.Push("event")
+	val, ok, err := q.Pop() // val = "event", ok = true
+
+	// Number of items currently on disk
+	onDisk := q.Spilled()
+
+	// Prevent unused variable warnings in example
+	_, _, _, _ = val, ok, err, onDisk
+}
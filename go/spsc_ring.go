@@ -0,0 +1,133 @@
+package typed
+
+import "sync/atomic"
+
+const cacheLineSize = 64
+
+// SPSCRing is a bounded, lock-free ring buffer for exactly one producer
+// goroutine and one consumer goroutine. Push methods must only be called
+// from the producer and Pop methods only from the consumer.
+type SPSCRing[T any] struct {
+	_    [cacheLineSize]byte
+	head atomic.Uint64 // next slot to read, written by the consumer
+	_    [cacheLineSize - 8]byte
+	tail atomic.Uint64 // next slot to write, written by the producer
+	_    [cacheLineSize - 8]byte
+
+	// cachedHead and cachedTail are private snapshots of the other side's
+	// index, so the hot path only touches the shared line when it looks full
+	// or empty.
+	cachedHead uint64 // producer-owned
+	_          [cacheLineSize - 8]byte
+	cachedTail uint64 // consumer-owned
+	_          [cacheLineSize - 8]byte
+
+	data []T
+	mask uint64
+}
+
+// NewSPSCRing creates a ring that holds up to capacity items.
+// The capacity must be a power of two.
+func NewSPSCRing[T any](capacity int) *SPSCRing[T] {
+	if capacity <= 0 || capacity&(capacity-1) != 0 {
+		panic("SPSCRing capacity must be a power of two")
+	}
+	return &SPSCRing[T]{
+		data: make([]T, capacity),
+		mask: uint64(capacity - 1),
+	}
+}
+
+// TryPush adds val to the ring. It returns false if the ring is full.
+func (r *SPSCRing[T]) TryPush(val T) bool {
+	tail := r.tail.Load()
+	if tail-r.cachedHead == uint64(len(r.data)) {
+		r.cachedHead = r.head.Load()
+		if tail-r.cachedHead == uint64(len(r.data)) {
+			return false
+		}
+	}
+	r.data[tail&r.mask] = val
+	r.tail.Store(tail + 1)
+	return true
+}
+
+// TryPushBatch adds as many items from vals as fit and returns how many
+// were added.
+func (r *SPSCRing[T]) TryPushBatch(vals []T) int {
+	tail := r.tail.Load()
+	free := uint64(len(r.data)) - (tail - r.cachedHead)
+	if free < uint64(len(vals)) {
+		r.cachedHead = r.head.Load()
+		free = uint64(len(r.data)) - (tail - r.cachedHead)
+	}
+	n := uint64(len(vals))
+	if n > free {
+		n = free
+	}
+	for i := uint64(0); i < n; i++ {
+		r.data[(tail+i)&r.mask] = vals[i]
+	}
+	if n > 0 {
+		r.tail.Store(tail + n)
+	}
+	return int(n)
+}
+
+// TryPop removes the oldest item. It returns false if the ring is empty.
+func (r *SPSCRing[T]) TryPop() (T, bool) {
+	var zero T
+	head := r.head.Load()
+	if head == r.cachedTail {
+		r.cachedTail = r.tail.Load()
+		if head == r.cachedTail {
+			return zero, false
+		}
+	}
+	idx := head & r.mask
+	val := r.data[idx]
+	r.data[idx] = zero
+	r.head.Store(head + 1)
+	return val, true
+}
+
+// TryPopBatch fills dst with up to len(dst) of the oldest items and
+// returns how many were removed.
+func (r *SPSCRing[T]) TryPopBatch(dst []T) int {
+	var zero T
+	head := r.head.Load()
+	avail := r.cachedTail - head
+	if avail < uint64(len(dst)) {
+		r.cachedTail = r.tail.Load()
+		avail = r.cachedTail - head
+	}
+	n := uint64(len(dst))
+	if n > avail {
+		n = avail
+	}
+	for i := uint64(0); i < n; i++ {
+		idx := (head + i) & r.mask
+		dst[i] = r.data[idx]
+		r.data[idx] = zero
+	}
+	if n > 0 {
+		r.head.Store(head + n)
+	}
+	return int(n)
+}
+
+// Size returns the number of items in the ring. The result is only a
+// snapshot when the other side is running concurrently.
+func (r *SPSCRing[T]) Size() int {
+	head := r.head.Load()
+	tail := r.tail.Load()
+	return int(tail - head)
+}
+
+func (r *SPSCRing[T]) Cap() int {
+	return len(r.data)
+}
+
+func (r *SPSCRing[T]) IsEmpty() bool {
+	return r.Size() == 0
+}
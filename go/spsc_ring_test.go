@@ -0,0 +1,296 @@
+package typed
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestSPSCRing(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		cap   int
+		steps []step
+	}{
+		{
+			name: "basic operations",
+			cap:  4,
+			steps: []step{
+				{"isEmpty", nil, true},
+				{"pop", nil, false},
+				{"push", 1, true},
+				{"push", 2, true},
+				{"size", nil, 2},
+				{"pop", nil, 1},
+				{"pop", nil, 2},
+				{"isEmpty", nil, true},
+			},
+		},
+		{
+			name: "full ring rejects push",
+			cap:  2,
+			steps: []step{
+				{"push", 1, true},
+				{"push", 2, true},
+				{"push", 3, false},
+				{"pop", nil, 1},
+				{"push", 3, true},
+				{"pop", nil, 2},
+				{"pop", nil, 3},
+			},
+		},
+		{
+			name: "batch operations with wrap-around",
+			cap:  4,
+			steps: []step{
+				{"pushBatch", []int{1, 2, 3}, 3},
+				{"popBatch", 2, []int{1, 2}},
+				{"pushBatch", []int{4, 5, 6, 7}, 3},
+				{"size", nil, 4},
+				{"popBatch", 8, []int{3, 4, 5, 6}},
+				{"popBatch", 1, []int{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewSPSCRing[int](tt.cap)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					if got := r.TryPush(step.value.(int)); got != step.expected.(bool) {
+						t.Errorf("step %d: push expected %v, got %v", i, step.expected, got)
+					}
+				case "pop":
+					val, ok := r.TryPop()
+					if step.expected != false {
+						if !ok || val != step.expected.(int) {
+							t.Errorf("step %d: pop expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+						}
+					} else if ok {
+						t.Errorf("step %d: pop expected to fail but succeeded with %v", i, val)
+					}
+				case "pushBatch":
+					if got := r.TryPushBatch(step.value.([]int)); got != step.expected.(int) {
+						t.Errorf("step %d: pushBatch expected %d, got %d", i, step.expected, got)
+					}
+				case "popBatch":
+					dst := make([]int, step.value.(int))
+					n := r.TryPopBatch(dst)
+					expected := step.expected.([]int)
+					if n != len(expected) {
+						t.Fatalf("step %d: popBatch expected %d items, got %d", i, len(expected), n)
+					}
+					for j, v := range expected {
+						if dst[j] != v {
+							t.Errorf("step %d: popBatch[%d] expected %d, got %d", i, j, v, dst[j])
+						}
+					}
+				case "size":
+					if got := r.Size(); got != step.expected.(int) {
+						t.Errorf("step %d: size expected %v, got %v", i, step.expected, got)
+					}
+				case "isEmpty":
+					if got := r.IsEmpty(); got != step.expected.(bool) {
+						t.Errorf("step %d: isEmpty expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestSPSCRing_InvalidCapacity(t *testing.T) {
+	for _, c := range []int{0, -1, 3, 6} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic for capacity %d", c)
+				}
+			}()
+			NewSPSCRing[int](c)
+		}()
+	}
+}
+
+func TestSPSCRing_Concurrent(t *testing.T) {
+	const n = 100000
+	r := NewSPSCRing[int](64)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		batch := make([]int, 0, 8)
+		for i := 0; i < n; {
+			if i%3 == 0 {
+				if r.TryPush(i) {
+					i++
+				} else {
+					runtime.Gosched()
+				}
+				continue
+			}
+			batch = batch[:0]
+			for j := i; j < n && len(batch) < cap(batch); j++ {
+				batch = append(batch, j)
+			}
+			k := r.TryPushBatch(batch)
+			if k == 0 {
+				runtime.Gosched()
+			}
+			i += k
+		}
+	}()
+
+	next := 0
+	dst := make([]int, 5)
+	for next < n {
+		if next%2 == 0 {
+			if v, ok := r.TryPop(); ok {
+				if v != next {
+					t.Fatalf("expected %d, got %d", next, v)
+				}
+				next++
+			} else {
+				runtime.Gosched()
+			}
+			continue
+		}
+		k := r.TryPopBatch(dst)
+		if k == 0 {
+			runtime.Gosched()
+		}
+		for _, v := range dst[:k] {
+			if v != next {
+				t.Fatalf("expected %d, got %d", next, v)
+			}
+			next++
+		}
+	}
+	wg.Wait()
+
+	if !r.IsEmpty() {
+		t.Errorf("expected ring to be empty, size is %d", r.Size())
+	}
+}
+
+func BenchmarkSPSCRing(b *testing.B) {
+	r := NewSPSCRing[int](1024)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < b.N; {
+			if _, ok := r.TryPop(); ok {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+		close(done)
+	}()
+	for i := 0; i < b.N; {
+		if r.TryPush(i) {
+			i++
+		} else {
+			runtime.Gosched()
+		}
+	}
+	<-done
+}
+
+func BenchmarkSPSCRing_Batch(b *testing.B) {
+	r := NewSPSCRing[int](1024)
+	done := make(chan struct{})
+	go func() {
+		dst := make([]int, 64)
+		for i := 0; i < b.N; {
+			n := r.TryPopBatch(dst)
+			if n == 0 {
+				runtime.Gosched()
+			}
+			i += n
+		}
+		close(done)
+	}()
+	src := make([]int, 64)
+	for i := 0; i < b.N; {
+		n := len(src)
+		if b.N-i < n {
+			n = b.N - i
+		}
+		k := r.TryPushBatch(src[:n])
+		if k == 0 {
+			runtime.Gosched()
+		}
+		i += k
+	}
+	<-done
+}
+
+func BenchmarkSPSCRing_MutexQueue(b *testing.B) {
+	var mu sync.Mutex
+	q := NewQueue[int]()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < b.N; {
+			mu.Lock()
+			_, ok := q.Pop()
+			mu.Unlock()
+			if ok {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+		close(done)
+	}()
+	for i := 0; i < b.N; i++ {
+		mu.Lock()
+		q.Push(i)
+		mu.Unlock()
+	}
+	<-done
+}
+
+func BenchmarkSPSCRing_Channel(b *testing.B) {
+	ch := make(chan int, 1024)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < b.N; i++ {
+			<-ch
+		}
+		close(done)
+	}()
+	for i := 0; i < b.N; i++ {
+		ch <- i
+	}
+	<-done
+}
+
+// Example of using SPSCRing
+func ExampleSPSCRing() {
+	// Create a ring with room for 8 items
+	r := NewSPSCRing[int](8)
+
+	// Producer side
+	r.TryPush(1)
+	r.TryPushBatch([]int{2, 3})
+
+	// Consumer side
+	val, ok := r.TryPop() // val = 1, ok = true
+	buf := make([]int, 4)
+	n := r.TryPopBatch(buf) // n = 2, buf[:n] = [2, 3]
+
+	// Prevent unused variable warnings in example
+	_, _, _ = val, ok, n
+}
@@ -2,13 +2,18 @@ package typed
 
 type StackOptions struct {
 	LimitOptions
+	GrowthOptions
+	ResizeHooks
 }
 
 type StackOption func(*StackOptions)
 
 func defaultStackOptions() StackOptions {
+	growth := DefaultGrowthOptions()
+	growth.InitialCapacity = 0 // stacks allocate on first Push
 	return StackOptions{
-		LimitOptions: DefaultLimitOptions(),
+		LimitOptions:  DefaultLimitOptions(),
+		GrowthOptions: growth,
 	}
 }
 
@@ -20,10 +25,27 @@ func WithStackLimitOptions(limitOpts ...LimitOption) StackOption {
 	}
 }
 
+func WithStackGrowthOptions(growthOpts ...GrowthOption) StackOption {
+	return func(so *StackOptions) {
+		for _, opt := range growthOpts {
+			opt(&so.GrowthOptions)
+		}
+	}
+}
+
+func WithStackResizeHooks(hooks ...ResizeHook) StackOption {
+	return func(so *StackOptions) {
+		for _, hook := range hooks {
+			hook(&so.ResizeHooks)
+		}
+	}
+}
+
 type Stack[T any] struct {
 	items   []T
 	pointer int
 	opts    StackOptions
+	stats   Stats
 }
 
 func NewStack[T any](options ...StackOption) *Stack[T] {
@@ -31,7 +53,10 @@ func NewStack[T any](options ...StackOption) *Stack[T] {
 	for _, opt := range options {
 		opt(&opts)
 	}
-	return &Stack[T]{opts: opts}
+	return &Stack[T]{
+		items: make([]T, 0, opts.InitialCapacity),
+		opts:  opts,
+	}
 }
 
 func (s *Stack[T]) Len() int      { return s.pointer }
@@ -40,11 +65,15 @@ func (s *Stack[T]) IsEmpty() bool { return s.pointer == 0 }
 
 func (s *Stack[T]) Push(item T) {
 	if s.pointer == len(s.items) {
+		if len(s.items) == cap(s.items) {
+			s.realloc(s.opts.nextCap(cap(s.items), s.pointer+1))
+		}
 		s.items = append(s.items, item)
 	} else {
 		s.items[s.pointer] = item
 	}
 	s.pointer++
+	s.stats.recordPush(s.pointer)
 }
 
 func (s *Stack[T]) Pop() (T, bool) {
@@ -53,6 +82,7 @@ func (s *Stack[T]) Pop() (T, bool) {
 		return zero, false
 	}
 	s.pointer--
+	s.stats.recordPop()
 	val := s.items[s.pointer]
 	s.items[s.pointer] = zero
 	if s.shouldShrink() {
@@ -80,18 +110,38 @@ func (s *Stack[T]) Reset() {
 	}
 }
 
+// Stats returns usage counters for the stack.
+func (s *Stack[T]) Stats() Stats {
+	return s.stats
+}
+
+// Reserve makes room for at least n items, so the stack does not grow
+// again until it holds more than n. Auto-shrink may still release the
+// space once usage drops.
+func (s *Stack[T]) Reserve(n int) {
+	if n > cap(s.items) {
+		s.realloc(n)
+	}
+}
+
 func (s *Stack[T]) Shrink() {
-	if s.pointer < cap(s.items) {
-		newItems := make([]T, s.pointer)
-		copy(newItems, s.items[:s.pointer])
-		s.items = newItems
+	if target := max(s.opts.shrinkTarget(s.pointer), s.opts.InitialCapacity); target < cap(s.items) {
+		s.realloc(target)
 	}
 }
 
+// realloc copies the items into a new slice with capacity newCap.
+func (s *Stack[T]) realloc(newCap int) {
+	oldCap := cap(s.items)
+	newItems := make([]T, s.pointer, newCap)
+	copy(newItems, s.items[:s.pointer])
+	s.items = newItems
+	recordResize[T](&s.stats, s.opts.ResizeHooks, oldCap, newCap, s.pointer)
+	s.opts.resized(newCap)
+}
+
 func (s *Stack[T]) shouldShrink() bool {
-	return s.opts.EnableAutoShrink &&
-		cap(s.items) > s.opts.ShrinkThresholdCap &&
-		float64(s.pointer) < float64(cap(s.items))*s.opts.ShrinkUsageRatio
+	return s.opts.shouldShrink(s.pointer, cap(s.items))
 }
 
 func (s *Stack[T]) ItemsCopy() []T {
@@ -0,0 +1,62 @@
+package typed
+
+import "unsafe"
+
+// Stats describes how a container has been used since it was created.
+type Stats struct {
+	Pushes      uint64 // Items added
+	Pops        uint64 // Items removed
+	PeakSize    int    // Largest number of items held at once
+	Grows       uint64 // Times the capacity increased
+	Shrinks     uint64 // Times the capacity decreased
+	BytesCopied uint64 // Bytes moved between backing arrays by resizes
+}
+
+// ResizeHooks are called after a container changes its capacity.
+type ResizeHooks struct {
+	OnGrow   func(oldCap, newCap int)
+	OnShrink func(oldCap, newCap int)
+}
+
+type ResizeHook func(*ResizeHooks)
+
+func WithOnGrow(fn func(oldCap, newCap int)) ResizeHook {
+	return func(h *ResizeHooks) {
+		h.OnGrow = fn
+	}
+}
+
+func WithOnShrink(fn func(oldCap, newCap int)) ResizeHook {
+	return func(h *ResizeHooks) {
+		h.OnShrink = fn
+	}
+}
+
+func (s *Stats) recordPush(size int) {
+	s.Pushes++
+	if size > s.PeakSize {
+		s.PeakSize = size
+	}
+}
+
+func (s *Stats) recordPop() {
+	s.Pops++
+}
+
+// recordResize counts a capacity change that copied n items of type T.
+func recordResize[T any](s *Stats, h ResizeHooks, oldCap, newCap, n int) {
+	var zero T
+	s.BytesCopied += uint64(n) * uint64(unsafe.Sizeof(zero))
+	switch {
+	case newCap > oldCap:
+		s.Grows++
+		if h.OnGrow != nil {
+			h.OnGrow(oldCap, newCap)
+		}
+	case newCap < oldCap:
+		s.Shrinks++
+		if h.OnShrink != nil {
+			h.OnShrink(oldCap, newCap)
+		}
+	}
+}
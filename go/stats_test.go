@@ -0,0 +1,225 @@
+package typed
+
+import (
+	"testing"
+	"unsafe"
+)
+
+type resizeEvent struct {
+	grow           bool
+	oldCap, newCap int
+}
+
+func TestStats(t *testing.T) {
+	var events []resizeEvent
+	hooks := []ResizeHook{
+		WithOnGrow(func(oldCap, newCap int) { events = append(events, resizeEvent{true, oldCap, newCap}) }),
+		WithOnShrink(func(oldCap, newCap int) { events = append(events, resizeEvent{false, oldCap, newCap}) }),
+	}
+	limits := []LimitOption{WithShrinkThresholdCap(4), WithShrinkHeadroom(0)}
+	growth := []GrowthOption{WithInitialCapacity(4)}
+
+	type container struct {
+		push  func(int)
+		pop   func()
+		stats func() Stats
+	}
+
+	tests := []struct {
+		name     string
+		new      func() container
+		pushes   int
+		pops     int
+		expected Stats
+		events   []resizeEvent
+	}{
+		{
+			name: "queue",
+			new: func() container {
+				q := NewQueue[int](WithQueueGrowthOptions(growth...), WithQueueLimitOptions(limits...), WithQueueResizeHooks(hooks...))
+				return container{q.Push, func() { q.Pop() }, q.Stats}
+			},
+			pushes: 10,
+			pops:   9,
+			// 4 -> 8 copies 4 items, 8 -> 16 copies 8, shrinking back to the
+			// initial capacity copies the remaining 3
+			expected: Stats{Pushes: 10, Pops: 9, PeakSize: 10, Grows: 2, Shrinks: 1, BytesCopied: 15 * uint64(unsafe.Sizeof(0))},
+			events:   []resizeEvent{{true, 4, 8}, {true, 8, 16}, {false, 16, 4}},
+		},
+		{
+			name: "deque",
+			new: func() container {
+				d := NewDeque[int](WithDequeGrowthOptions(growth...), WithDequeLimitOptions(limits...), WithDequeResizeHooks(hooks...))
+				return container{d.PushFront, func() { d.PopBack() }, d.Stats}
+			},
+			pushes:   10,
+			pops:     9,
+			expected: Stats{Pushes: 10, Pops: 9, PeakSize: 10, Grows: 2, Shrinks: 1, BytesCopied: 15 * uint64(unsafe.Sizeof(0))},
+			events:   []resizeEvent{{true, 4, 8}, {true, 8, 16}, {false, 16, 4}},
+		},
+		{
+			name: "stack",
+			new: func() container {
+				s := NewStack[int](WithStackGrowthOptions(growth...), WithStackLimitOptions(limits...), WithStackResizeHooks(hooks...))
+				return container{s.Push, func() { s.Pop() }, s.Stats}
+			},
+			pushes:   10,
+			pops:     9,
+			expected: Stats{Pushes: 10, Pops: 9, PeakSize: 10, Grows: 2, Shrinks: 1, BytesCopied: 15 * uint64(unsafe.Sizeof(0))},
+			events:   []resizeEvent{{true, 4, 8}, {true, 8, 16}, {false, 16, 4}},
+		},
+		{
+			name: "heap",
+			new: func() container {
+				h := NewHeap[int](func(a, b int) bool { return a < b }, WithHeapGrowthOptions(growth...), WithHeapResizeHooks(hooks...))
+				return container{h.Push, func() { h.Pop() }, h.Stats}
+			},
+			pushes:   10,
+			pops:     3,
+			expected: Stats{Pushes: 10, Pops: 3, PeakSize: 10, Grows: 2, BytesCopied: 12 * uint64(unsafe.Sizeof(0))},
+			events:   []resizeEvent{{true, 4, 8}, {true, 8, 16}},
+		},
+		{
+			name: "set",
+			new: func() container {
+				s := NewSet[int]()
+				return container{func(v int) { s.Add(v % 7) }, func() { s.Remove(0) }, s.Stats}
+			},
+			pushes:   10,
+			pops:     2,
+			expected: Stats{Pushes: 7, Pops: 1, PeakSize: 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events = nil
+			c := tt.new()
+			for i := 0; i < tt.pushes; i++ {
+				c.push(i)
+			}
+			for i := 0; i < tt.pops; i++ {
+				c.pop()
+			}
+			if got := c.stats(); got != tt.expected {
+				t.Errorf("expected stats %+v, got %+v", tt.expected, got)
+			}
+			if len(events) != len(tt.events) {
+				t.Fatalf("expected events %v, got %v", tt.events, events)
+			}
+			for i, e := range tt.events {
+				if events[i] != e {
+					t.Errorf("event %d: expected %v, got %v", i, e, events[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStats_Segmented(t *testing.T) {
+	var grows, shrinks int
+	q := NewQueue[int](WithQueueSegmentSize(4), WithQueueResizeHooks(
+		WithOnGrow(func(oldCap, newCap int) { grows++ }),
+		WithOnShrink(func(oldCap, newCap int) { shrinks++ }),
+	))
+	for i := 0; i < 40; i++ {
+		q.Push(i)
+	}
+	for i := 0; i < 40; i++ {
+		q.Pop()
+	}
+	st := q.Stats()
+	if st.Pushes != 40 || st.Pops != 40 || st.PeakSize != 40 {
+		t.Errorf("unexpected counters %+v", st)
+	}
+	if st.BytesCopied != 0 {
+		t.Errorf("expected the segmented backend to copy nothing, got %d bytes", st.BytesCopied)
+	}
+	if int(st.Grows) != grows || int(st.Shrinks) != shrinks || grows == 0 || shrinks == 0 {
+		t.Errorf("expected hooks to match counters, got %d/%d grows and %d/%d shrinks",
+			grows, st.Grows, shrinks, st.Shrinks)
+	}
+}
+
+func TestStats_HooksSeeNewCap(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(hooks ...ResizeHook) (push func(int), pop func(), capacity func() int)
+	}{
+		{"queue", func(hooks ...ResizeHook) (func(int), func(), func() int) {
+			q := NewQueue[int](WithQueueLimitOptions(WithShrinkThresholdCap(4)), WithQueueResizeHooks(hooks...))
+			return q.Push, func() { q.Pop() }, q.Cap
+		}},
+		{"segmented queue", func(hooks ...ResizeHook) (func(int), func(), func() int) {
+			q := NewQueue[int](WithQueueSegmentSize(4), WithQueueResizeHooks(hooks...))
+			return q.Push, func() { q.Pop() }, q.Cap
+		}},
+		{"deque", func(hooks ...ResizeHook) (func(int), func(), func() int) {
+			d := NewDeque[int](WithDequeLimitOptions(WithShrinkThresholdCap(4)), WithDequeResizeHooks(hooks...))
+			return d.PushBack, func() { d.PopFront() }, d.Cap
+		}},
+		{"segmented deque", func(hooks ...ResizeHook) (func(int), func(), func() int) {
+			d := NewDeque[int](WithDequeSegmentSize(4), WithDequeResizeHooks(hooks...))
+			return d.PushBack, func() { d.PopFront() }, d.Cap
+		}},
+		{"stack", func(hooks ...ResizeHook) (func(int), func(), func() int) {
+			s := NewStack[int](WithStackLimitOptions(WithShrinkThresholdCap(4)), WithStackResizeHooks(hooks...))
+			return s.Push, func() { s.Pop() }, s.Cap
+		}},
+		{"heap", func(hooks ...ResizeHook) (func(int), func(), func() int) {
+			h := NewHeap[int](func(a, b int) bool { return a < b }, WithHeapResizeHooks(hooks...))
+			return h.Push, func() { h.Pop() }, h.Cap
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var capacity func() int
+			events := 0
+			check := func(oldCap, newCap int) {
+				events++
+				if got := capacity(); got != newCap {
+					t.Errorf("hook for %d -> %d saw Cap() %d", oldCap, newCap, got)
+				}
+			}
+			var push func(int)
+			var pop func()
+			push, pop, capacity = tt.new(WithOnGrow(check), WithOnShrink(check))
+			for i := 0; i < 40; i++ {
+				push(i)
+			}
+			for i := 0; i < 40; i++ {
+				pop()
+			}
+			if events == 0 {
+				t.Errorf("expected resize hooks to run")
+			}
+		})
+	}
+}
+
+func TestStats_NoAllocations(t *testing.T) {
+	q := NewQueue[int]()
+	q.Reserve(64)
+	d := NewDeque[int]()
+	d.Reserve(64)
+	s := NewStack[int]()
+	s.Reserve(64)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for i := 0; i < 32; i++ {
+			q.Push(i)
+			d.PushBack(i)
+			s.Push(i)
+		}
+		for i := 0; i < 32; i++ {
+			q.Pop()
+			d.PopFront()
+			s.Pop()
+		}
+		_, _, _ = q.Stats(), d.Stats(), s.Stats()
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
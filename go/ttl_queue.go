@@ -0,0 +1,165 @@
+package typed
+
+import "time"
+
+type TTLQueueOptions struct {
+	QueueOptions               // Options for the underlying ring buffer
+	DefaultTTL   time.Duration // TTL used by Push, 0 for no expiry
+	Clock        func() time.Time
+}
+
+type TTLQueueOption func(*TTLQueueOptions)
+
+func defaultTTLQueueOptions() TTLQueueOptions {
+	return TTLQueueOptions{
+		QueueOptions: defaultQueueOptions(),
+		Clock:        time.Now,
+	}
+}
+
+func WithTTLQueueOptions(queueOpts ...QueueOption) TTLQueueOption {
+	return func(to *TTLQueueOptions) {
+		for _, opt := range queueOpts {
+			opt(&to.QueueOptions)
+		}
+	}
+}
+
+func WithDefaultTTL(ttl time.Duration) TTLQueueOption {
+	if ttl < 0 {
+		panic("Default TTL must not be negative")
+	}
+	return func(to *TTLQueueOptions) {
+		to.DefaultTTL = ttl
+	}
+}
+
+// WithTTLClock replaces time.Now as the source of the current time.
+func WithTTLClock(now func() time.Time) TTLQueueOption {
+	if now == nil {
+		panic("TTL clock must not be nil")
+	}
+	return func(to *TTLQueueOptions) {
+		to.Clock = now
+	}
+}
+
+type ttlEntry[T any] struct {
+	val      T
+	deadline time.Time // zero for no expiry
+}
+
+// TTLQueue is a FIFO queue whose items expire after a per-item TTL.
+// Expired items are dropped when they reach the front, or by PurgeExpired,
+// and passed to the onExpire callback.
+type TTLQueue[T any] struct {
+	queue    *Queue[ttlEntry[T]]
+	opts     TTLQueueOptions
+	onExpire func(T)
+}
+
+// NewTTLQueue creates a TTLQueue. onExpire may be nil.
+func NewTTLQueue[T any](onExpire func(T), opts ...TTLQueueOption) *TTLQueue[T] {
+	o := defaultTTLQueueOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &TTLQueue[T]{
+		queue:    NewQueue[ttlEntry[T]](func(qo *QueueOptions) { *qo = o.QueueOptions }),
+		opts:     o,
+		onExpire: onExpire,
+	}
+}
+
+// Push adds val with the default TTL.
+func (q *TTLQueue[T]) Push(val T) {
+	q.PushWithTTL(val, q.opts.DefaultTTL)
+}
+
+// PushWithTTL adds val, which expires ttl from now. A ttl of 0 or less
+// never expires.
+func (q *TTLQueue[T]) PushWithTTL(val T, ttl time.Duration) {
+	e := ttlEntry[T]{val: val}
+	if ttl > 0 {
+		e.deadline = q.opts.Clock().Add(ttl)
+	}
+	q.queue.Push(e)
+}
+
+// Pop removes and returns the oldest item that has not expired. Expired
+// items in front of it are dropped, reported to onExpire and counted in
+// expired.
+func (q *TTLQueue[T]) Pop() (val T, expired int, ok bool) {
+	expired = q.dropExpired(q.opts.Clock())
+	e, ok := q.queue.Pop()
+	return e.val, expired, ok
+}
+
+// Peek returns the oldest item that has not expired. Like Pop, it drops
+// and counts the expired items in front of it.
+func (q *TTLQueue[T]) Peek() (val T, expired int, ok bool) {
+	expired = q.dropExpired(q.opts.Clock())
+	e, ok := q.queue.Peek()
+	return e.val, expired, ok
+}
+
+// PurgeExpired drops every item that has expired at now, keeping the
+// order of the rest, and returns how many were dropped. onExpire runs
+// once the queue holds only the live items.
+func (q *TTLQueue[T]) PurgeExpired(now time.Time) int {
+	items := q.queue.Snapshot()
+	var expired []ttlEntry[T]
+	items.Range(func(_ int, e ttlEntry[T]) bool {
+		if e.expired(now) {
+			expired = append(expired, e)
+		}
+		return true
+	})
+	if len(expired) == 0 {
+		return 0
+	}
+
+	q.queue.Reset()
+	items.Range(func(_ int, e ttlEntry[T]) bool {
+		if !e.expired(now) {
+			q.queue.Push(e)
+		}
+		return true
+	})
+	for _, e := range expired {
+		q.expire(e)
+	}
+	return len(expired)
+}
+
+// Size returns the number of items held, including expired items that
+// have not been dropped yet.
+func (q *TTLQueue[T]) Size() int {
+	return q.queue.Size()
+}
+
+// IsEmpty reports whether the queue holds no items, expired or not.
+func (q *TTLQueue[T]) IsEmpty() bool {
+	return q.queue.IsEmpty()
+}
+
+func (q *TTLQueue[T]) dropExpired(now time.Time) int {
+	for n := 0; ; n++ {
+		e, ok := q.queue.Peek()
+		if !ok || !e.expired(now) {
+			return n
+		}
+		q.queue.Pop()
+		q.expire(e)
+	}
+}
+
+func (q *TTLQueue[T]) expire(e ttlEntry[T]) {
+	if q.onExpire != nil {
+		q.onExpire(e.val)
+	}
+}
+
+func (e ttlEntry[T]) expired(now time.Time) bool {
+	return !e.deadline.IsZero() && !now.Before(e.deadline)
+}
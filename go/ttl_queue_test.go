@@ -0,0 +1,231 @@
+package typed
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTTLQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		opts  []TTLQueueOption
+		steps []step
+	}{
+		{
+			name: "no expiry by default",
+			steps: []step{
+				{"push", 1, nil},
+				{"push", 2, nil},
+				{"advance", time.Hour, nil},
+				{"pop", nil, 1},
+				{"pop", nil, 2},
+				{"pop", nil, nil},
+				{"expired", nil, []int(nil)},
+			},
+		},
+		{
+			name: "pop skips expired items",
+			steps: []step{
+				{"pushTTL", [2]int{1, 10}, nil},
+				{"pushTTL", [2]int{2, 30}, nil},
+				{"pushTTL", [2]int{3, 10}, nil},
+				{"push", 4, nil},
+				{"advance", 10 * time.Second, nil},
+				{"size", nil, 4},
+				{"peek", nil, 2},
+				{"skipped", nil, 1},
+				{"expired", nil, []int{1}},
+				{"pop", nil, 2},
+				{"skipped", nil, 0},
+				{"pop", nil, 4},
+				{"skipped", nil, 1},
+				{"expired", nil, []int{1, 3}},
+				{"isEmpty", nil, true},
+			},
+		},
+		{
+			name: "purge keeps order of live items",
+			steps: []step{
+				{"pushTTL", [2]int{1, 5}, nil},
+				{"pushTTL", [2]int{2, 20}, nil},
+				{"pushTTL", [2]int{3, 5}, nil},
+				{"pushTTL", [2]int{4, 0}, nil},
+				{"pushTTL", [2]int{5, 5}, nil},
+				{"purge", 4 * time.Second, 0},
+				{"purge", 5 * time.Second, 3},
+				{"expired", nil, []int{1, 3, 5}},
+				{"size", nil, 2},
+				{"pop", nil, 2},
+				{"pop", nil, 4},
+			},
+		},
+		{
+			name: "default TTL",
+			opts: []TTLQueueOption{WithDefaultTTL(time.Minute)},
+			steps: []step{
+				{"push", 1, nil},
+				{"advance", 30 * time.Second, nil},
+				{"push", 2, nil},
+				{"advance", 30 * time.Second, nil},
+				{"pop", nil, 2},
+				{"skipped", nil, 1},
+				{"expired", nil, []int{1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Unix(1000, 0)
+			now := start
+			var expired []int
+			var skipped int
+			q := NewTTLQueue[int](func(v int) { expired = append(expired, v) },
+				append(tt.opts, WithTTLClock(func() time.Time { return now }))...)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					q.Push(step.value.(int))
+				case "pushTTL":
+					args := step.value.([2]int)
+					q.PushWithTTL(args[0], time.Duration(args[1])*time.Second)
+				case "advance":
+					now = now.Add(step.value.(time.Duration))
+				case "pop":
+					var val int
+					var ok bool
+					val, skipped, ok = q.Pop()
+					if step.expected == nil {
+						if ok {
+							t.Errorf("step %d: pop expected to fail, got %v", i, val)
+						}
+					} else if !ok || val != step.expected.(int) {
+						t.Errorf("step %d: pop expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+					}
+				case "peek":
+					var val int
+					var ok bool
+					val, skipped, ok = q.Peek()
+					if !ok || val != step.expected.(int) {
+						t.Errorf("step %d: peek expected %v, got %v (ok=%v)", i, step.expected, val, ok)
+					}
+				case "purge":
+					if got := q.PurgeExpired(start.Add(step.value.(time.Duration))); got != step.expected.(int) {
+						t.Errorf("step %d: purge expected %v, got %v", i, step.expected, got)
+					}
+				case "skipped":
+					if skipped != step.expected.(int) {
+						t.Errorf("step %d: skipped expected %v, got %v", i, step.expected, skipped)
+					}
+				case "expired":
+					if !reflect.DeepEqual(expired, step.expected.([]int)) {
+						t.Errorf("step %d: expired expected %v, got %v", i, step.expected, expired)
+					}
+				case "size":
+					if got := q.Size(); got != step.expected.(int) {
+						t.Errorf("step %d: size expected %v, got %v", i, step.expected, got)
+					}
+				case "isEmpty":
+					if got := q.IsEmpty(); got != step.expected.(bool) {
+						t.Errorf("step %d: isEmpty expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestTTLQueue_FIFO(t *testing.T) {
+	now := time.Unix(0, 0)
+	q := NewTTLQueue[int](nil, WithTTLClock(func() time.Time { return now }),
+		WithTTLQueueOptions(WithQueueSegmentSize(8)))
+
+	// Every third item expires quickly; the rest must come out in order.
+	for i := 0; i < 100; i++ {
+		ttl := time.Hour
+		if i%3 == 0 {
+			ttl = time.Second
+		}
+		q.PushWithTTL(i, ttl)
+	}
+	now = now.Add(time.Minute)
+	if got := q.PurgeExpired(now); got != 34 {
+		t.Fatalf("expected 34 purged, got %d", got)
+	}
+	for i := 0; i < 100; i++ {
+		if i%3 == 0 {
+			continue
+		}
+		if val, _, ok := q.Pop(); !ok || val != i {
+			t.Fatalf("expected %d, got %v (ok=%v)", i, val, ok)
+		}
+	}
+	if !q.IsEmpty() {
+		t.Errorf("expected empty queue, got size %d", q.Size())
+	}
+}
+
+func TestTTLQueue_PurgeCallback(t *testing.T) {
+	now := time.Unix(0, 0)
+	var q *TTLQueue[int]
+	calls := 0
+	q = NewTTLQueue[int](func(v int) {
+		// The queue already holds only the live items, plus earlier pushes
+		if want := 3 + calls; q.Size() != want {
+			t.Errorf("expected %d items during the callback, got %d", want, q.Size())
+		}
+		calls++
+		q.PushWithTTL(100+v, 0)
+	}, WithTTLClock(func() time.Time { return now }))
+
+	for i := 0; i < 5; i++ {
+		ttl := time.Hour
+		if i%2 == 1 {
+			ttl = time.Second
+		}
+		q.PushWithTTL(i, ttl)
+	}
+	if got := q.PurgeExpired(now.Add(time.Minute)); got != 2 {
+		t.Fatalf("expected 2 purged, got %d", got)
+	}
+
+	// Items pushed by the callback go behind every live item
+	var got []int
+	for !q.IsEmpty() {
+		val, _, _ := q.Pop()
+		got = append(got, val)
+	}
+	if want := []int{0, 2, 4, 101, 103}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// Example of using TTLQueue
+func ExampleTTLQueue() {
+	// Drop notifications that wait longer than 5 minutes
+	q := NewTTLQueue[string](func(msg string) {
+		// Called for every expired message
+	}, WithDefaultTTL(5*time.Minute))
+
+	q.Push("welcome")
+	q.PushWithTTL("flash sale", 30*time.Second)
+
+	// Expired items are skipped, and counted
+	msg, expired, ok := q.Pop()
+
+	// Drop everything that has expired without popping
+	dropped := q.PurgeExpired(time.Now())
+
+	// Prevent unused variable warnings in example
+	_, _, _, _ = msg, expired, ok, dropped
+}
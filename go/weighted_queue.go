@@ -0,0 +1,204 @@
+package typed
+
+type WeightedQueueOptions struct {
+	QueueOptions                        // Options for every per-class queue
+	Quantum         int                 // Cost credited per unit of weight each round
+	NewShrinkPolicy func() ShrinkPolicy // Creates the ShrinkPolicy of each class queue
+}
+
+type WeightedQueueOption func(*WeightedQueueOptions)
+
+func defaultWeightedQueueOptions() WeightedQueueOptions {
+	return WeightedQueueOptions{
+		QueueOptions: defaultQueueOptions(),
+		Quantum:      1,
+	}
+}
+
+// WithWeightedQueueOptions configures the queue of every class.
+func WithWeightedQueueOptions(queueOpts ...QueueOption) WeightedQueueOption {
+	return func(wo *WeightedQueueOptions) {
+		for _, opt := range queueOpts {
+			opt(&wo.QueueOptions)
+		}
+	}
+}
+
+// WithQuantum sets the cost a class may spend per unit of weight each
+// round. Use roughly the typical item cost, so a class is not skipped
+// many rounds before it can afford its next item.
+func WithQuantum(n int) WeightedQueueOption {
+	if n <= 0 {
+		panic("Quantum must be greater than 0")
+	}
+	return func(wo *WeightedQueueOptions) {
+		wo.Quantum = n
+	}
+}
+
+// WithClassShrinkPolicy gives every class queue its own policy from
+// newPolicy. A policy set through WithWeightedQueueOptions would be shared
+// by all classes, so NewWeightedQueue rejects it.
+func WithClassShrinkPolicy(newPolicy func() ShrinkPolicy) WeightedQueueOption {
+	return func(wo *WeightedQueueOptions) {
+		wo.NewShrinkPolicy = newPolicy
+	}
+}
+
+type weightedClass[T any] struct {
+	queue   *Queue[T]
+	weight  int
+	deficit int
+	credit  bool // deficit already topped up for this round
+	served  uint64
+}
+
+// WeightedQueue serves classes of items in proportion to their weights
+// using deficit round robin. Each class has a FIFO queue; classes without
+// a weight set count as weight 1.
+type WeightedQueue[K comparable, T any] struct {
+	classes map[K]*weightedClass[T]
+	order   *Queue[K] // classes with pending items, in serving order
+	cost    func(T) int
+	size    int
+	served  uint64
+	opts    WeightedQueueOptions
+}
+
+// NewWeightedQueue creates a WeightedQueue. cost returns the non-negative
+// cost of serving an item; a nil cost charges 1 per item.
+func NewWeightedQueue[K comparable, T any](cost func(T) int, opts ...WeightedQueueOption) *WeightedQueue[K, T] {
+	o := defaultWeightedQueueOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ShrinkPolicy != nil {
+		panic("WeightedQueue classes cannot share a ShrinkPolicy, use WithClassShrinkPolicy")
+	}
+	if cost == nil {
+		cost = func(T) int { return 1 }
+	}
+	return &WeightedQueue[K, T]{
+		classes: make(map[K]*weightedClass[T]),
+		order:   NewQueue[K](),
+		cost:    cost,
+		opts:    o,
+	}
+}
+
+func (w *WeightedQueue[K, T]) class(key K) *weightedClass[T] {
+	c, ok := w.classes[key]
+	if !ok {
+		c = &weightedClass[T]{
+			queue: NewQueue[T](func(qo *QueueOptions) {
+				*qo = w.opts.QueueOptions
+				if w.opts.NewShrinkPolicy != nil {
+					qo.ShrinkPolicy = w.opts.NewShrinkPolicy()
+				}
+			}),
+			weight: 1,
+		}
+		w.classes[key] = c
+	}
+	return c
+}
+
+// SetWeight changes the share of key. It takes effect from the next round
+// of that class.
+func (w *WeightedQueue[K, T]) SetWeight(key K, weight int) {
+	if weight <= 0 {
+		panic("Weight must be greater than 0")
+	}
+	w.class(key).weight = weight
+}
+
+func (w *WeightedQueue[K, T]) Weight(key K) int {
+	if c, ok := w.classes[key]; ok {
+		return c.weight
+	}
+	return 1
+}
+
+func (w *WeightedQueue[K, T]) Push(key K, val T) {
+	c := w.class(key)
+	if c.queue.IsEmpty() {
+		w.order.Push(key)
+	}
+	c.queue.Push(val)
+	w.size++
+}
+
+// Pop removes the next item in deficit round robin order.
+func (w *WeightedQueue[K, T]) Pop() (K, T, bool) {
+	var zero T
+	for {
+		key, ok := w.order.Peek()
+		if !ok {
+			return key, zero, false
+		}
+		c := w.classes[key]
+		if !c.credit {
+			c.deficit += c.weight * w.opts.Quantum
+			c.credit = true
+		}
+		front, _ := c.queue.Peek()
+		cost := w.cost(front)
+		if cost > c.deficit {
+			// Out of credit, move on to the next class
+			c.credit = false
+			w.order.Pop()
+			w.order.Push(key)
+			continue
+		}
+		val, _ := c.queue.Pop()
+		c.deficit -= cost
+		c.served += uint64(cost)
+		w.served += uint64(cost)
+		w.size--
+		if c.queue.IsEmpty() {
+			// Idle classes do not bank credit
+			c.deficit = 0
+			c.credit = false
+			w.order.Pop()
+		}
+		return key, val, true
+	}
+}
+
+// Shares returns the fraction of the total cost served so far that went
+// to each class.
+func (w *WeightedQueue[K, T]) Shares() map[K]float64 {
+	shares := make(map[K]float64, len(w.classes))
+	for key, c := range w.classes {
+		if w.served == 0 {
+			shares[key] = 0
+			continue
+		}
+		shares[key] = float64(c.served) / float64(w.served)
+	}
+	return shares
+}
+
+// ResetShares starts a new measurement period for Shares.
+func (w *WeightedQueue[K, T]) ResetShares() {
+	for _, c := range w.classes {
+		c.served = 0
+	}
+	w.served = 0
+}
+
+// Len returns the number of items pending for key.
+func (w *WeightedQueue[K, T]) Len(key K) int {
+	if c, ok := w.classes[key]; ok {
+		return c.queue.Size()
+	}
+	return 0
+}
+
+func (w *WeightedQueue[K, T]) Size() int {
+	return w.size
+}
+
+func (w *WeightedQueue[K, T]) IsEmpty() bool {
+	return w.size == 0
+}
@@ -0,0 +1,236 @@
+package typed
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWeightedQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+	type item struct {
+		key string
+		val int
+	}
+
+	tests := []struct {
+		name  string
+		cost  func(int) int
+		opts  []WeightedQueueOption
+		steps []step
+	}{
+		{
+			name: "empty",
+			steps: []step{
+				{"isEmpty", nil, true},
+				{"pop", nil, nil},
+				{"weight", "a", 1},
+			},
+		},
+		{
+			name: "equal weights alternate",
+			steps: []step{
+				{"push", item{"a", 1}, nil},
+				{"push", item{"a", 2}, nil},
+				{"push", item{"b", 3}, nil},
+				{"push", item{"b", 4}, nil},
+				{"pop", nil, item{"a", 1}},
+				{"pop", nil, item{"b", 3}},
+				{"pop", nil, item{"a", 2}},
+				{"pop", nil, item{"b", 4}},
+				{"isEmpty", nil, true},
+			},
+		},
+		{
+			name: "weights give proportional bursts",
+			steps: []step{
+				{"weight", item{"a", 2}, nil},
+				{"pushMany", item{"a", 0}, 4},
+				{"pushMany", item{"b", 10}, 4},
+				{"len", "a", 4},
+				{"pop", nil, item{"a", 0}},
+				{"pop", nil, item{"a", 1}},
+				{"pop", nil, item{"b", 10}},
+				{"pop", nil, item{"a", 2}},
+				{"pop", nil, item{"a", 3}},
+				{"pop", nil, item{"b", 11}},
+				{"pop", nil, item{"b", 12}},
+				{"size", nil, 1},
+			},
+		},
+		{
+			name: "costly items wait for credit",
+			cost: func(v int) int { return v },
+			opts: []WeightedQueueOption{WithQuantum(2)},
+			steps: []step{
+				{"push", item{"a", 5}, nil},
+				{"push", item{"b", 1}, nil},
+				{"push", item{"b", 1}, nil},
+				{"push", item{"b", 1}, nil},
+				{"push", item{"b", 1}, nil},
+				// a needs three rounds of 2 before it can afford 5
+				{"pop", nil, item{"b", 1}},
+				{"pop", nil, item{"b", 1}},
+				{"pop", nil, item{"b", 1}},
+				{"pop", nil, item{"b", 1}},
+				{"pop", nil, item{"a", 5}},
+			},
+		},
+		{
+			name: "idle class does not bank credit",
+			steps: []step{
+				{"weight", item{"a", 3}, nil},
+				{"push", item{"a", 1}, nil},
+				{"push", item{"b", 2}, nil},
+				{"pop", nil, item{"a", 1}},
+				{"push", item{"a", 3}, nil},
+				{"push", item{"a", 4}, nil},
+				{"pop", nil, item{"b", 2}},
+				{"pop", nil, item{"a", 3}},
+				{"pop", nil, item{"a", 4}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewWeightedQueue[string, int](tt.cost, tt.opts...)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					it := step.value.(item)
+					q.Push(it.key, it.val)
+				case "pushMany":
+					it := step.value.(item)
+					for j := 0; j < step.expected.(int); j++ {
+						q.Push(it.key, it.val+j)
+					}
+				case "pop":
+					key, val, ok := q.Pop()
+					if step.expected == nil {
+						if ok {
+							t.Errorf("step %d: pop expected to fail, got %v=%v", i, key, val)
+						}
+					} else if got := (item{key, val}); !ok || got != step.expected.(item) {
+						t.Errorf("step %d: pop expected %v, got %v (ok=%v)", i, step.expected, got, ok)
+					}
+				case "weight":
+					if it, ok := step.value.(item); ok {
+						q.SetWeight(it.key, it.val)
+					} else if got := q.Weight(step.value.(string)); got != step.expected.(int) {
+						t.Errorf("step %d: weight expected %v, got %v", i, step.expected, got)
+					}
+				case "len":
+					if got := q.Len(step.value.(string)); got != step.expected.(int) {
+						t.Errorf("step %d: len expected %v, got %v", i, step.expected, got)
+					}
+				case "size":
+					if got := q.Size(); got != step.expected.(int) {
+						t.Errorf("step %d: size expected %v, got %v", i, step.expected, got)
+					}
+				case "isEmpty":
+					if got := q.IsEmpty(); got != step.expected.(bool) {
+						t.Errorf("step %d: isEmpty expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedQueue_Shares(t *testing.T) {
+	q := NewWeightedQueue[string, int](nil)
+	q.SetWeight("gold", 7)
+	q.SetWeight("silver", 2)
+	q.SetWeight("bronze", 1)
+	for _, k := range []string{"gold", "silver", "bronze"} {
+		for i := 0; i < 2000; i++ {
+			q.Push(k, i)
+		}
+	}
+
+	check := func(expected map[string]float64) {
+		t.Helper()
+		shares := q.Shares()
+		for k, want := range expected {
+			if math.Abs(shares[k]-want) > 0.01 {
+				t.Errorf("expected %s share %.2f, got %.3f", k, want, shares[k])
+			}
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		q.Pop()
+	}
+	check(map[string]float64{"gold": 0.7, "silver": 0.2, "bronze": 0.1})
+
+	// Reweight at runtime
+	q.ResetShares()
+	q.SetWeight("gold", 1)
+	for i := 0; i < 300; i++ {
+		q.Pop()
+	}
+	check(map[string]float64{"gold": 0.25, "silver": 0.5, "bronze": 0.25})
+}
+
+func TestWeightedQueue_InvalidWeight(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for zero weight")
+		}
+	}()
+	NewWeightedQueue[string, int](nil).SetWeight("a", 0)
+}
+
+func TestWeightedQueue_ShrinkPolicyPerClass(t *testing.T) {
+	var policies []*countingShrinkPolicy
+	q := NewWeightedQueue[string, int](nil, WithClassShrinkPolicy(func() ShrinkPolicy {
+		p := &countingShrinkPolicy{ShrinkPolicy: NewRatioShrinkPolicy(0, 0.25, 0.5)}
+		policies = append(policies, p)
+		return p
+	}))
+	for i := 0; i < 20; i++ {
+		q.Push("a", i)
+		q.Push("b", i)
+	}
+	if len(policies) != 2 {
+		t.Fatalf("expected a policy per class, got %d", len(policies))
+	}
+	for i, p := range policies {
+		if p.resizes == 0 {
+			t.Errorf("policy %d: expected its own class queue to report resizes", i)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a shared ShrinkPolicy")
+		}
+	}()
+	NewWeightedQueue[string, int](nil, WithWeightedQueueOptions(WithQueueLimitOptions(
+		WithShrinkPolicy(NewRatioShrinkPolicy(0, 0.25, 0.5)))))
+}
+
+// Example of using WeightedQueue
+func ExampleWeightedQueue() {
+	// Serve traffic classes 70/20/10, charging each request by its size
+	q := NewWeightedQueue[string, []byte](func(b []byte) int { return len(b) }, WithQuantum(1500))
+	q.SetWeight("interactive", 7)
+	q.SetWeight("batch", 2)
+	q.SetWeight("background", 1)
+
+	q.Push("batch", []byte("report"))
+	class, payload, ok := q.Pop()
+
+	// Fraction of the served cost per class
+	shares := q.Shares()
+
+	// Prevent unused variable warnings in example
+	_, _, _, _ = class, payload, ok, shares
+}
@@ -0,0 +1,94 @@
+package typed
+
+import "sync"
+
+// WorkQueue hands out keys to workers with the semantics of client-go's
+// workqueue: a key is queued at most once however often it is added, and
+// a key added while a worker is processing it is queued again, once, when
+// the worker calls Done. It is safe for concurrent use.
+type WorkQueue[K comparable] struct {
+	mu           sync.Mutex
+	cond         *sync.Cond
+	queue        *Queue[K]
+	dirty        *Set[K] // keys waiting to be processed
+	processing   *Set[K] // keys handed out by Get and not yet Done
+	shuttingDown bool
+}
+
+func NewWorkQueue[K comparable](opts ...QueueOption) *WorkQueue[K] {
+	w := &WorkQueue[K]{
+		queue:      NewQueue[K](opts...),
+		dirty:      NewSet[K](),
+		processing: NewSet[K](),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// Add marks key as needing processing. It is ignored after ShutDown.
+func (w *WorkQueue[K]) Add(key K) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.shuttingDown || w.dirty.Contains(key) {
+		return
+	}
+	w.dirty.Add(key)
+	if w.processing.Contains(key) {
+		// Done queues it again
+		return
+	}
+	w.queue.Push(key)
+	w.cond.Signal()
+}
+
+// Get blocks until a key is available and hands it to the caller, who
+// must call Done with it when finished. shutdown is true once the queue
+// has been shut down and drained.
+func (w *WorkQueue[K]) Get() (key K, shutdown bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.queue.IsEmpty() && !w.shuttingDown {
+		w.cond.Wait()
+	}
+	if w.queue.IsEmpty() {
+		return key, true
+	}
+	key, _ = w.queue.Pop()
+	w.processing.Add(key)
+	w.dirty.Remove(key)
+	return key, false
+}
+
+// Done marks key as processed, queueing it again if it was added while
+// being processed.
+func (w *WorkQueue[K]) Done(key K) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.processing.Remove(key)
+	if w.dirty.Contains(key) {
+		w.queue.Push(key)
+		w.cond.Signal()
+	}
+}
+
+// Len returns the number of keys waiting to be handed out.
+func (w *WorkQueue[K]) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.queue.Size()
+}
+
+// ShutDown stops the queue accepting keys. Workers blocked in Get wake up
+// and receive the remaining keys, then shutdown = true.
+func (w *WorkQueue[K]) ShutDown() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.shuttingDown = true
+	w.cond.Broadcast()
+}
+
+func (w *WorkQueue[K]) ShuttingDown() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.shuttingDown
+}
@@ -0,0 +1,201 @@
+package typed
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkQueue(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "duplicate adds are merged",
+			steps: []step{
+				{"add", "a", nil},
+				{"add", "b", nil},
+				{"add", "a", nil},
+				{"len", nil, 2},
+				{"get", nil, "a"},
+				{"get", nil, "b"},
+				{"len", nil, 0},
+			},
+		},
+		{
+			name: "add while processing requeues once on done",
+			steps: []step{
+				{"add", "a", nil},
+				{"get", nil, "a"},
+				{"add", "a", nil},
+				{"add", "a", nil},
+				{"len", nil, 0},
+				{"done", "a", nil},
+				{"len", nil, 1},
+				{"get", nil, "a"},
+				{"done", "a", nil},
+				{"len", nil, 0},
+			},
+		},
+		{
+			name: "done without re-add forgets the key",
+			steps: []step{
+				{"add", "a", nil},
+				{"get", nil, "a"},
+				{"done", "a", nil},
+				{"add", "a", nil},
+				{"len", nil, 1},
+			},
+		},
+		{
+			name: "shutdown drains then reports",
+			steps: []step{
+				{"add", "a", nil},
+				{"shutdown", nil, nil},
+				{"shuttingDown", nil, true},
+				{"add", "b", nil},
+				{"get", nil, "a"},
+				{"get", nil, nil},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWorkQueue[string]()
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "add":
+					w.Add(step.value.(string))
+				case "get":
+					key, shutdown := w.Get()
+					if step.expected == nil {
+						if !shutdown {
+							t.Errorf("step %d: get expected shutdown, got %v", i, key)
+						}
+					} else if shutdown || key != step.expected.(string) {
+						t.Errorf("step %d: get expected %v, got %v (shutdown=%v)", i, step.expected, key, shutdown)
+					}
+				case "done":
+					w.Done(step.value.(string))
+				case "len":
+					if got := w.Len(); got != step.expected.(int) {
+						t.Errorf("step %d: len expected %v, got %v", i, step.expected, got)
+					}
+				case "shutdown":
+					w.ShutDown()
+				case "shuttingDown":
+					if got := w.ShuttingDown(); got != step.expected.(bool) {
+						t.Errorf("step %d: shuttingDown expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkQueue_ShutDownWakesWorkers(t *testing.T) {
+	w := NewWorkQueue[int]()
+	done := make(chan bool)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, shutdown := w.Get()
+			done <- shutdown
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	w.ShutDown()
+	for i := 0; i < 3; i++ {
+		select {
+		case shutdown := <-done:
+			if !shutdown {
+				t.Error("expected shutdown")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("worker not woken by ShutDown")
+		}
+	}
+}
+
+func TestWorkQueue_NoConcurrentProcessing(t *testing.T) {
+	w := NewWorkQueue[int]()
+	var mu sync.Mutex
+	active := map[int]bool{}
+	processed := map[int]int{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				key, shutdown := w.Get()
+				if shutdown {
+					return
+				}
+				mu.Lock()
+				if active[key] {
+					t.Errorf("key %d handed to two workers at once", key)
+				}
+				active[key] = true
+				processed[key]++
+				mu.Unlock()
+
+				time.Sleep(time.Microsecond)
+
+				mu.Lock()
+				active[key] = false
+				mu.Unlock()
+				w.Done(key)
+			}
+		}()
+	}
+
+	for i := 0; i < 2000; i++ {
+		w.Add(i % 10)
+	}
+	for w.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	w.ShutDown()
+	wg.Wait()
+
+	for key := 0; key < 10; key++ {
+		if processed[key] == 0 {
+			t.Errorf("key %d never processed", key)
+		}
+	}
+}
+
+// Example of using WorkQueue
+func ExampleWorkQueue() {
+	w := NewWorkQueue[string]()
+
+	// Producers add keys; repeated adds of a pending key are merged
+	w.Add("default/web")
+	w.Add("default/web")
+
+	// Workers
+	go func() {
+		for {
+			key, shutdown := w.Get()
+			if shutdown {
+				return
+			}
+			// Reconcile key, then mark it done so it can be queued again
+			w.Done(key)
+		}
+	}()
+
+	w.ShutDown()
+}
@@ -0,0 +1,141 @@
+package typed
+
+import "sync/atomic"
+
+// wsArray is the circular array of a WorkStealingDeque. Slots hold
+// pointers so that a thief reading a slot the owner is overwriting never
+// sees a torn value; the item behind a pointer is never modified.
+type wsArray[T any] struct {
+	slots []atomic.Pointer[T]
+	mask  int64
+}
+
+func newWSArray[T any](capacity int) *wsArray[T] {
+	return &wsArray[T]{
+		slots: make([]atomic.Pointer[T], capacity),
+		mask:  int64(capacity - 1),
+	}
+}
+
+func (a *wsArray[T]) get(i int64) *T {
+	return a.slots[i&a.mask].Load()
+}
+
+func (a *wsArray[T]) put(i int64, p *T) {
+	a.slots[i&a.mask].Store(p)
+}
+
+// grow returns a copy of the items in [top, bottom) in an array twice as
+// large. Thieves may keep reading the old array.
+func (a *wsArray[T]) grow(bottom, top int64) *wsArray[T] {
+	b := newWSArray[T](2 * len(a.slots))
+	for i := top; i < bottom; i++ {
+		b.put(i, a.get(i))
+	}
+	return b
+}
+
+// WorkStealingDeque is a Chase-Lev work-stealing deque. The owning
+// goroutine pushes and pops at the bottom, and any number of other
+// goroutines steal from the top, all without locks. Push and Pop must only
+// be called by the owner; Steal, Size and IsEmpty are safe from anywhere.
+//
+// The array doubles when full and never shrinks.
+type WorkStealingDeque[T any] struct {
+	_      [cacheLineSize]byte
+	top    atomic.Int64 // next item to steal, advanced by CAS
+	_      [cacheLineSize - 8]byte
+	bottom atomic.Int64 // next free slot, written only by the owner
+	_      [cacheLineSize - 8]byte
+	array  atomic.Pointer[wsArray[T]]
+}
+
+// NewWorkStealingDeque creates a deque with room for capacity items before
+// it first grows. The capacity must be a power of two.
+func NewWorkStealingDeque[T any](capacity int) *WorkStealingDeque[T] {
+	if capacity <= 0 || capacity&(capacity-1) != 0 {
+		panic("WorkStealingDeque capacity must be a power of two")
+	}
+	d := &WorkStealingDeque[T]{}
+	d.array.Store(newWSArray[T](capacity))
+	return d
+}
+
+// Push adds val at the bottom. Only the owner may call Push.
+func (d *WorkStealingDeque[T]) Push(val T) {
+	b := d.bottom.Load()
+	t := d.top.Load()
+	a := d.array.Load()
+	if b-t >= int64(len(a.slots)) {
+		a = a.grow(b, t)
+		d.array.Store(a)
+	}
+	a.put(b, &val)
+	d.bottom.Store(b + 1)
+}
+
+// Pop removes the item at the bottom, the one pushed last. Only the owner
+// may call Pop.
+func (d *WorkStealingDeque[T]) Pop() (T, bool) {
+	var zero T
+	b := d.bottom.Load() - 1
+	a := d.array.Load()
+	// Announce the claim on slot b before looking at top. Go's atomics
+	// are sequentially consistent, which provides the store-load fence
+	// the algorithm needs.
+	d.bottom.Store(b)
+	t := d.top.Load()
+	if t > b {
+		d.bottom.Store(b + 1)
+		return zero, false
+	}
+	p := a.get(b)
+	if t == b {
+		// Last item: race the thieves for it
+		won := d.top.CompareAndSwap(t, t+1)
+		d.bottom.Store(b + 1)
+		if !won {
+			return zero, false
+		}
+	}
+	a.put(b, nil)
+	return *p, true
+}
+
+// Steal removes the item at the top, the oldest one. It returns false if
+// the deque is empty.
+func (d *WorkStealingDeque[T]) Steal() (T, bool) {
+	var zero T
+	for {
+		t := d.top.Load()
+		b := d.bottom.Load()
+		if t >= b {
+			return zero, false
+		}
+		a := d.array.Load()
+		p := a.get(t)
+		if d.top.CompareAndSwap(t, t+1) {
+			return *p, true
+		}
+		// Lost to the owner or another thief, try the next item
+	}
+}
+
+// Size returns the number of items. It may be stale by the time it returns
+// if other goroutines are active.
+func (d *WorkStealingDeque[T]) Size() int {
+	n := d.bottom.Load() - d.top.Load()
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+func (d *WorkStealingDeque[T]) IsEmpty() bool {
+	return d.Size() == 0
+}
+
+// Cap returns the current size of the circular array.
+func (d *WorkStealingDeque[T]) Cap() int {
+	return len(d.array.Load().slots)
+}
@@ -0,0 +1,211 @@
+package typed
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkStealingDeque(t *testing.T) {
+	type step struct {
+		op       string
+		value    any
+		expected any
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "owner is LIFO, thieves are FIFO",
+			steps: []step{
+				{"isEmpty", nil, true},
+				{"pop", nil, nil},
+				{"steal", nil, nil},
+				{"push", 1, nil},
+				{"push", 2, nil},
+				{"push", 3, nil},
+				{"size", nil, 3},
+				{"pop", nil, 3},
+				{"steal", nil, 1},
+				{"pop", nil, 2},
+				{"pop", nil, nil},
+				{"steal", nil, nil},
+			},
+		},
+		{
+			name: "grows when full",
+			steps: []step{
+				{"pushMany", 9, nil},
+				{"cap", nil, 16},
+				{"steal", nil, 0},
+				{"steal", nil, 1},
+				{"pop", nil, 8},
+				{"size", nil, 6},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewWorkStealingDeque[int](4)
+
+			for i, step := range tt.steps {
+				switch step.op {
+				case "push":
+					d.Push(step.value.(int))
+				case "pushMany":
+					for j := 0; j < step.value.(int); j++ {
+						d.Push(j)
+					}
+				case "pop", "steal":
+					var val int
+					var ok bool
+					if step.op == "pop" {
+						val, ok = d.Pop()
+					} else {
+						val, ok = d.Steal()
+					}
+					if step.expected == nil {
+						if ok {
+							t.Errorf("step %d: %s expected to fail, got %v", i, step.op, val)
+						}
+					} else if !ok || val != step.expected.(int) {
+						t.Errorf("step %d: %s expected %v, got %v (ok=%v)", i, step.op, step.expected, val, ok)
+					}
+				case "size":
+					if got := d.Size(); got != step.expected.(int) {
+						t.Errorf("step %d: size expected %v, got %v", i, step.expected, got)
+					}
+				case "cap":
+					if got := d.Cap(); got != step.expected.(int) {
+						t.Errorf("step %d: cap expected %v, got %v", i, step.expected, got)
+					}
+				case "isEmpty":
+					if got := d.IsEmpty(); got != step.expected.(bool) {
+						t.Errorf("step %d: isEmpty expected %v, got %v", i, step.expected, got)
+					}
+				default:
+					t.Fatalf("step %d: unknown op %s", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkStealingDeque_InvalidCapacity(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for capacity that is not a power of two")
+		}
+	}()
+	NewWorkStealingDeque[int](3)
+}
+
+// TestWorkStealingDeque_Stress has the owner push and pop while thieves
+// steal, and checks that every item is taken exactly once. Run it with
+// -race to check the memory ordering.
+func TestWorkStealingDeque_Stress(t *testing.T) {
+	const items = 20000
+	const thieves = 4
+	d := NewWorkStealingDeque[int](2)
+	seen := make([]atomic.Int32, items)
+	var taken atomic.Int64
+
+	var wg sync.WaitGroup
+	var done atomic.Bool
+	for i := 0; i < thieves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !done.Load() || !d.IsEmpty() {
+				if val, ok := d.Steal(); ok {
+					seen[val].Add(1)
+					taken.Add(1)
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < items; i++ {
+		d.Push(i)
+		if i%3 == 0 {
+			if val, ok := d.Pop(); ok {
+				seen[val].Add(1)
+				taken.Add(1)
+			}
+		}
+		if i%64 == 0 {
+			runtime.Gosched()
+		}
+	}
+	for {
+		val, ok := d.Pop()
+		if !ok {
+			break
+		}
+		seen[val].Add(1)
+		taken.Add(1)
+	}
+	done.Store(true)
+	wg.Wait()
+
+	if got := taken.Load(); got != items {
+		t.Errorf("expected %d items taken, got %d", items, got)
+	}
+	for i := range seen {
+		if n := seen[i].Load(); n != 1 {
+			t.Fatalf("item %d taken %d times", i, n)
+		}
+	}
+}
+
+// Example of a small fork/join pool built on WorkStealingDeque: each
+// worker runs tasks from its own deque and steals from the others when
+// it runs out.
+func ExampleWorkStealingDeque() {
+	const workers = 4
+	deques := make([]*WorkStealingDeque[func()], workers)
+	for i := range deques {
+		deques[i] = NewWorkStealingDeque[func()](64)
+	}
+
+	// All tasks start on worker 0; the others have to steal them
+	var pending sync.WaitGroup
+	var stolen atomic.Int64
+	for i := 0; i < 100; i++ {
+		pending.Add(1)
+		deques[0].Push(func() { pending.Done() })
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			own := deques[w]
+			for {
+				task, ok := own.Pop()
+				for v := 1; !ok && v < workers; v++ {
+					if task, ok = deques[(w+v)%workers].Steal(); ok {
+						stolen.Add(1)
+					}
+				}
+				if !ok {
+					return // nothing left anywhere
+				}
+				task()
+			}
+		}(w)
+	}
+
+	pending.Wait()
+	wg.Wait()
+
+	// Prevent unused variable warnings in example
+	_ = stolen.Load()
+}